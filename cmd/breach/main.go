@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/sha1"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/certified-juniors/AtomHack/internal/env"
+	"github.com/certified-juniors/AtomHack/internal/password"
+)
+
+const usage = `usage: breach <command> [flags]
+
+commands:
+  build  build a bloom filter of breached passwords from a HIBP SHA-1 dump
+  check  tell whether a password is in the filter
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	filter := fs.String("filter", env.String("PASSWORD_BREACH_FILTER", "breached.bloom"), "bloom filter file")
+
+	switch os.Args[1] {
+	case "build":
+		in := fs.String("in", "", "HIBP dump: a file of HASH:COUNT lines or a directory of range files")
+		fp := fs.Float64("fp", 0.001, "false positive rate")
+		minCount := fs.Int("min-count", 1, "skip hashes seen fewer times, shrinks the filter")
+		_ = fs.Parse(os.Args[2:])
+
+		if *in == "" || *fp <= 0 || *fp >= 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		// the dump is read twice: to size the filter and to fill it
+		var n uint64
+		err := password.ReadHIBP(*in, *minCount, func([sha1.Size]byte) { n++ })
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "build:", err)
+			os.Exit(1)
+		}
+
+		bloom := password.NewBloomFilter(n, *fp)
+		if err = password.ReadHIBP(*in, *minCount, bloom.AddHash); err != nil {
+			fmt.Fprintln(os.Stderr, "build:", err)
+			os.Exit(1)
+		}
+
+		if err = writeFilter(*filter, bloom); err != nil {
+			fmt.Fprintln(os.Stderr, "build:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%d hashes written to %s\n", n, *filter)
+	case "check":
+		_ = fs.Parse(os.Args[2:])
+		if fs.NArg() != 1 {
+			fs.Usage()
+			os.Exit(2)
+		}
+
+		bloom, err := password.LoadBloomFilter(*filter)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "check:", err)
+			os.Exit(1)
+		}
+		if bloom.Breached([]byte(fs.Arg(0))) {
+			fmt.Println("breached")
+			os.Exit(1)
+		}
+		fmt.Println("not found")
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// writeFilter replaces the filter file atomically, so a running service
+// never reads a half written one.
+func writeFilter(path string, bloom *password.BloomFilter) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if _, err = bloom.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
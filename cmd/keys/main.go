@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	auth_usecase "github.com/certified-juniors/AtomHack/internal/auth/usecase"
+	"github.com/certified-juniors/AtomHack/internal/env"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage: keys <command> [flags]
+
+commands:
+  rotate  generate a new signing key and schedule retirement of the current ones
+  list    print keys of the key ring
+`
+
+func main() {
+	_ = godotenv.Load()
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	dir := fs.String("dir", env.String("JWT_KEYS_DIR", "keys"), "key ring directory")
+
+	switch os.Args[1] {
+	case "rotate":
+		alg := fs.String("alg", "RS256", "signing algorithm: RS256, ES256 or EdDSA")
+		publish := fs.Duration("publish", 5*time.Minute, "delay before the new key signs tokens, covers JWKS caching")
+		overlap := fs.Duration("overlap", 24*time.Hour, "how long the old keys keep verifying tokens after the new key is active")
+		_ = fs.Parse(os.Args[2:])
+
+		entry, err := auth_usecase.RotateKeyRing(*dir, *alg, *publish, *overlap)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "rotate:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("new key %s (%s) active at %s\n", entry.Kid, entry.Alg, entry.ActivatesAt.Format(time.RFC3339))
+	case "list":
+		_ = fs.Parse(os.Args[2:])
+
+		manifest, err := auth_usecase.ReadKeyManifest(*dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "list:", err)
+			os.Exit(1)
+		}
+		for _, k := range manifest.Keys {
+			retires := "-"
+			if k.RetiresAt != nil {
+				retires = k.RetiresAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%s\t%s\tactivates %s\tretires %s\n", k.Kid, k.Alg, k.ActivatesAt.Format(time.RFC3339), retires)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
@@ -21,4 +21,55 @@ CREATE TRIGGER modify_user_updated_at
     BEFORE UPDATE
     ON "user"
     FOR EACH ROW
-    EXECUTE PROCEDURE public.moddatetime(updated_at);
\ No newline at end of file
+    EXECUTE PROCEDURE public.moddatetime(updated_at);
+
+CREATE TABLE user_totp
+(
+    user_id    INT PRIMARY KEY REFERENCES "user" (id) ON DELETE CASCADE,
+    secret     BYTEA NOT NULL,
+    enabled    BOOL        DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER modify_user_totp_updated_at
+    BEFORE UPDATE
+    ON user_totp
+    FOR EACH ROW
+    EXECUTE PROCEDURE public.moddatetime(updated_at);
+
+
+CREATE TABLE user_recovery_code
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INT   NOT NULL REFERENCES user_totp (user_id) ON DELETE CASCADE,
+    lookup     TEXT  NOT NULL,
+    code_hash  BYTEA NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX user_recovery_code_user_id_idx ON user_recovery_code (user_id, lookup);
+
+CREATE TABLE user_webauthn_credential
+(
+    credential_id    BYTEA PRIMARY KEY,
+    user_id          INT    NOT NULL REFERENCES "user" (id) ON DELETE CASCADE,
+    public_key       BYTEA  NOT NULL,
+    attestation_type TEXT   NOT NULL,
+    aaguid           BYTEA,
+    sign_count       BIGINT NOT NULL DEFAULT 0,
+    transports       TEXT[] NOT NULL DEFAULT '{}',
+    backup_eligible  BOOL   NOT NULL DEFAULT FALSE,
+    backup_state     BOOL   NOT NULL DEFAULT FALSE,
+    created_at       TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at       TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX user_webauthn_credential_user_id_idx ON user_webauthn_credential (user_id);
+
+CREATE TRIGGER modify_user_webauthn_credential_updated_at
+    BEFORE UPDATE
+    ON user_webauthn_credential
+    FOR EACH ROW
+    EXECUTE PROCEDURE public.moddatetime(updated_at);
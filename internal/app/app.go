@@ -13,6 +13,7 @@ import (
 	auth_usecase "github.com/certified-juniors/AtomHack/internal/auth/usecase"
 	"github.com/certified-juniors/AtomHack/internal/connectors/postgres"
 	"github.com/certified-juniors/AtomHack/internal/connectors/redis"
+	"github.com/certified-juniors/AtomHack/internal/domain"
 	logs "github.com/certified-juniors/AtomHack/internal/logger"
 	"github.com/certified-juniors/AtomHack/internal/middleware"
 
@@ -46,18 +47,45 @@ func StartServer() {
 	ar := auth_postgres.NewAuthPostgresqlRepository(pc, ctx)
 
 	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
+	authParams := auth_usecase.GetAuthParams()
+
+	var keyRing *auth_usecase.KeyRing
+	if authParams.JWTKeysDir != "" {
+		kr, err := auth_usecase.LoadKeyRing(authParams.JWTKeysDir)
+		if err != nil {
+			logs.LogFatal(logs.Logger, "app", "StartServer", err, "Failed to load JWT key ring")
+		}
+		kr.Watch(authParams.JWTKeysReload)
+		keyRing = kr
+	} else {
+		signingKey, err := auth_usecase.LoadSigningKey(authParams.JWTAlgorithm, authParams.JWTPrivateKeyFile, authParams.JWTKeyID, jwtSecret)
+		if err != nil {
+			logs.LogFatal(logs.Logger, "app", "StartServer", err, "Failed to load JWT signing key")
+		}
+		keyRing = auth_usecase.NewStaticKeyRing(signingKey)
+	}
 
-	au := auth_usecase.NewAuthUsecase(ar, sr, jwtSecret)
+	au := auth_usecase.NewAuthUsecase(ar, sr, jwtSecret, keyRing, authParams)
 
-	auth_http.NewAuthHandler(authMiddlewareRouter, mainRouter, au)
+	auth_http.NewAuthHandler(authMiddlewareRouter, mainRouter, au, authParams.AntiEnumeration)
 
 	docs.SwaggerInfo.Host = os.Getenv("SWAGGER_ADDR")
 	docs.SwaggerInfo.Schemes = []string{os.Getenv("SWAGGER_SCHEME")}
 	mainRouter.PathPrefix("/swagger").Handler(httpSwagger.WrapHandler)
 	mw := middleware.NewAuth(au)
 
+	var rateLimitStore domain.RateLimitStore
+	if os.Getenv("RATE_LIMIT_BACKEND") == "memory" {
+		rateLimitStore = middleware.NewMemoryRateLimitStore()
+	} else {
+		rateLimitStore = middleware.NewRedisRateLimitStore(rc)
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimitStore, middleware.GetRateLimits())
+
 	authMiddlewareRouter.Use(mw.IsAuth)
 	mainRouter.Use(accessLogger.AccessLogMiddleware)
+	mainRouter.Use(middleware.LimitBody)
+	mainRouter.Use(rateLimiter.Limit)
 	//mainRouter.Use(mux.CORSMethodMiddleware(mainRouter))
 	//mainRouter.Use(middleware.CORS)
 
@@ -3,7 +3,9 @@ package http
 import (
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"github.com/certified-juniors/AtomHack/internal/auth/delivery/smtp"
+	"math"
 	"math/big"
 	"net/http"
 	"net/mail"
@@ -19,20 +21,49 @@ import (
 
 type AuthHandler struct {
 	AuthUsecase domain.AuthUsecase
+	// antiEnumeration hides whether an email is registered
+	antiEnumeration bool
 }
 
-func NewAuthHandler(authMwRouter *mux.Router, mainRouter *mux.Router, u domain.AuthUsecase) {
+func NewAuthHandler(authMwRouter *mux.Router, mainRouter *mux.Router, u domain.AuthUsecase, antiEnumeration bool) {
 	handler := &AuthHandler{
-		AuthUsecase: u,
+		AuthUsecase:     u,
+		antiEnumeration: antiEnumeration,
 	}
 
+	mainRouter.HandleFunc("/.well-known/jwks.json", handler.JWKS).Methods(http.MethodGet, http.MethodOptions)
+	mainRouter.HandleFunc("/oauth/introspect", handler.Introspect).Methods(http.MethodPost, http.MethodOptions)
+	mainRouter.HandleFunc("/oauth/revoke", handler.Revoke).Methods(http.MethodPost, http.MethodOptions)
 	mainRouter.HandleFunc("/api/v1/auth/login", handler.Login).Methods(http.MethodPost, http.MethodOptions)
 	mainRouter.HandleFunc("/api/v1/auth/register", handler.Register).Methods(http.MethodPost, http.MethodOptions)
 	mainRouter.HandleFunc("/api/v1/auth/confirm", handler.Confirm).Methods(http.MethodPost, http.MethodOptions)
+	mainRouter.HandleFunc("/api/v1/auth/confirm/resend", handler.ResendCode).Methods(http.MethodPost, http.MethodOptions)
+	mainRouter.HandleFunc("/api/v1/auth/refresh", handler.Refresh).Methods(http.MethodPost, http.MethodOptions)
 	mainRouter.HandleFunc("/api/v1/auth/me", handler.Me).Methods(http.MethodGet, http.MethodOptions)
+	mainRouter.HandleFunc("/api/v1/auth/password/forgot", handler.ForgotPassword).Methods(http.MethodPost, http.MethodOptions)
+	mainRouter.HandleFunc("/api/v1/auth/password/reset", handler.ResetPassword).Methods(http.MethodPost, http.MethodOptions)
 
 	authMwRouter.HandleFunc("/v1/auth/check", handler.CheckAuth).Methods(http.MethodPost, http.MethodOptions)
 	authMwRouter.HandleFunc("/v1/auth/logout", handler.Logout).Methods(http.MethodPost, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/password", handler.ChangePassword).Methods(http.MethodPut, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/sessions", handler.GetSessions).Methods(http.MethodGet, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/sessions", handler.DeleteOtherSessions).Methods(http.MethodDelete, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/sessions/{id}", handler.DeleteSession).Methods(http.MethodDelete, http.MethodOptions)
+
+	mainRouter.HandleFunc("/api/v1/auth/login/mfa", handler.LoginMFA).Methods(http.MethodPost, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/mfa/totp/enroll", handler.EnrollTOTP).Methods(http.MethodPost, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/mfa/totp/verify", handler.ActivateTOTP).Methods(http.MethodPost, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/mfa/totp", handler.DisableTOTP).Methods(http.MethodDelete, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/mfa/recovery-codes", handler.CountRecoveryCodes).Methods(http.MethodGet, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/mfa/recovery-codes", handler.RegenerateRecoveryCodes).Methods(http.MethodPost, http.MethodOptions)
+
+	mainRouter.HandleFunc("/api/v1/auth/magic-link", handler.RequestMagicLink).Methods(http.MethodPost, http.MethodOptions)
+	mainRouter.HandleFunc("/api/v1/auth/magic-link/verify", handler.LoginMagicLink).Methods(http.MethodPost, http.MethodOptions)
+
+	mainRouter.HandleFunc("/api/v1/auth/webauthn/login/begin", handler.BeginWebAuthnLogin).Methods(http.MethodPost, http.MethodOptions)
+	mainRouter.HandleFunc("/api/v1/auth/webauthn/login/finish", handler.FinishWebAuthnLogin).Methods(http.MethodPost, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/webauthn/register/begin", handler.BeginWebAuthnRegistration).Methods(http.MethodPost, http.MethodOptions)
+	authMwRouter.HandleFunc("/v1/auth/webauthn/register/finish", handler.FinishWebAuthnRegistration).Methods(http.MethodPost, http.MethodOptions)
 }
 
 // Login godoc
@@ -42,10 +73,13 @@ func NewAuthHandler(authMwRouter *mux.Router, mainRouter *mux.Router, u domain.A
 //	@Tags			Auth
 //	@Accept			json
 //	@Param			body	body		domain.Credentials	true	"user credentials"
-//	@Success		200		{object}	object{body=object{id=int}}
+//	@Success		200		{object}	object{body=object{id=int,mfaRequired=bool,challengeToken=string}}
 //	@Failure		400		{object}	object{err=string}
 //	@Failure		403		{object}	object{err=string}
 //	@Failure		404		{object}	object{err=string}
+//	@Failure		409		{object}	object{err=string}
+//	@Failure		423		{object}	object{err=string}
+//	@Failure		429		{object}	object{err=string}
 //	@Failure		500		{object}	object{err=string}
 //	@Router			/api/v1/auth/login [post]
 func (a *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -73,8 +107,27 @@ func (a *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	credentials.Email = strings.TrimSpace(credentials.Email)
+	credentials.IP = domain.ClientIP(r)
 
 	session, userID, err := a.AuthUsecase.Login(credentials)
+	var blocked *domain.LoginBlockedError
+	if errors.As(err, &blocked) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		if blocked.Notify {
+			a.sendLockoutMail(credentials.Email, blocked.RetryAfter)
+		}
+	}
+	if errors.Is(err, domain.ErrMFARequired) {
+		domain.WriteResponse(
+			w,
+			map[string]interface{}{
+				"mfaRequired":    true,
+				"challengeToken": session.Token,
+			},
+			http.StatusOK,
+		)
+		return
+	}
 	if err != nil {
 		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
 		logs.LogError(logs.Logger, "auth/http", "Login", err, "Failed to login")
@@ -82,15 +135,7 @@ func (a *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 	logs.Logger.Debug("auth/http Login: session:", session)
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    session.Token,
-		Expires:  session.ExpiresAt,
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteNoneMode,
-		Secure:   true,
-	})
+	a.startSession(w, r, session)
 
 	domain.WriteResponse(
 		w,
@@ -101,10 +146,20 @@ func (a *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
+// sendLockoutMail tells the owner that the account got locked. The login is
+// rejected anyway, so a failed mail is only logged.
+func (a *AuthHandler) sendLockoutMail(email string, lockout time.Duration) {
+	body := "Из-за нескольких неудачных попыток входа ваш аккаунт временно заблокирован на " +
+		strconv.Itoa(int(math.Ceil(lockout.Minutes()))) + " мин. Если это были не вы, смените пароль."
+	if err := smtp.SendMailToClient("Аккаунт заблокирован", body, email); err != nil {
+		logs.LogError(logs.Logger, "auth/http", "Login", err, "Failed to send lockout notification")
+	}
+}
+
 // Logout godoc
 //
 //	@Summary		logout user
-//	@Description	delete current session and nullify cookie
+//	@Description	delete current session with its refresh token and nullify cookies
 //	@Tags			Auth
 //	@Success		204
 //	@Failure		400	{object}	object{err=string}
@@ -123,29 +178,66 @@ func (a *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    "",
-		Expires:  time.Now(),
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteNoneMode,
-		Secure:   true,
-	})
+	clearSessionCookies(w)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Refresh godoc
+//
+//	@Summary		refresh session
+//	@Description	exchange refresh token from cookie or body for new access and refresh tokens, reuse of old refresh token revokes the whole session
+//	@Tags			Auth
+//	@Accept			json
+//	@Param			body	body		domain.RefreshRequest	false	"refresh token, if not sent in cookie"
+//	@Success		200		{object}	object{body=object{id=int}}
+//	@Failure		400		{object}	object{err=string}
+//	@Failure		404		{object}	object{err=string}
+//	@Failure		500		{object}	object{err=string}
+//	@Router			/api/v1/auth/refresh [post]
+func (a *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req domain.RefreshRequest
+	if c, err := r.Cookie("refresh_token"); err == nil {
+		req.RefreshToken = c.Value
+	} else if r.ContentLength != 0 {
+		if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+			domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+			logs.LogError(logs.Logger, "auth/http", "Refresh", err, "Failed to decode json from body")
+			return
+		}
+		defer domain.CloseAndAlert(r.Body, "auth/http", "Refresh")
+	}
+
+	session, userID, err := a.AuthUsecase.Refresh(req.RefreshToken)
+	if err != nil {
+		clearSessionCookies(w)
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "Refresh", err, "Failed to refresh session")
+		return
+	}
+
+	a.startSession(w, r, session)
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"id": userID,
+		},
+		http.StatusOK,
+	)
+}
+
 // Register godoc
 //
 //	@Summary		register user
-//	@Description	add new user to db and return it id
+//	@Description	add new user to db and return it id, in anti-enumeration mode answer 202 without id both for new and registered emails
 //	@Tags			Auth
 //	@Produce		json
 //	@Accept			json
 //	@Param			body	body		domain.UserWithoutId	true	"user credentials"
 //	@Success		200		{object}	object{body=object{id=int}}
-//	@Failure		400		{object}	object{err=string}
+//	@Success		202
+//	@Failure		400		{object}	object{err=string,violations=[]domain.PasswordViolation}
 //	@Failure		403		{object}	object{err=string}
 //	@Failure		500		{object}	object{err=string}
 //	@Router			/api/v1/auth/register [post]
@@ -175,8 +267,19 @@ func (a *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var id int
-	if id, err = a.AuthUsecase.Register(user); err != nil {
-		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+	id, err = a.AuthUsecase.Register(user)
+	if errors.Is(err, domain.ErrAlreadyExists) && a.antiEnumeration {
+		// the owner learns about the attempt, the client does not
+		err = smtp.SendMailToClient("Регистрация в AtomHack",
+			"Кто-то пытался зарегистрироваться с вашим адресом. Если это были вы, войдите в аккаунт или восстановите пароль.", user.Email)
+		if err != nil {
+			logs.LogError(logs.Logger, "auth/http", "Register.register", err, "Failed to notify owner")
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		domain.WriteUsecaseError(w, err)
 		logs.LogError(logs.Logger, "auth/http", "Register.register", err, "Failed to register")
 		return
 	}
@@ -202,6 +305,11 @@ func (a *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if a.antiEnumeration {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
 	domain.WriteResponse(
 		w,
 		map[string]interface{}{
@@ -215,7 +323,7 @@ func (a *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 //
 //	@Summary		confirm user
 //	@Description	confirm user
-//	@Param			body	body		domain.ConfirmPair	true	"user id and verification code"
+//	@Param			body	body		domain.ConfirmPair	true	"user id or email and verification code"
 //	@Tags			Auth
 //	@Success		204
 //	@Failure		400	{object}	object{err=string}
@@ -230,6 +338,7 @@ func (a *AuthHandler) Confirm(w http.ResponseWriter, r *http.Request) {
 		logs.LogError(logs.Logger, "auth/http", "CheckAuth", err, "Failed to decode json from body")
 		return
 	}
+	cp.Email = strings.TrimSpace(cp.Email)
 
 	var session domain.Session
 	if session, err = a.AuthUsecase.ConfirmUser(cp); err != nil {
@@ -238,15 +347,58 @@ func (a *AuthHandler) Confirm(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    session.Token,
-		Expires:  session.ExpiresAt,
-		Path:     "/",
-		HttpOnly: true,
-		SameSite: http.SameSiteNoneMode,
-		Secure:   true,
-	})
+	a.startSession(w, r, session)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendCode godoc
+//
+//	@Summary		resend confirmation code
+//	@Description	generate new confirmation code and send it to user email
+//	@Param			body	body	domain.ConfirmResend	true	"user id or email"
+//	@Tags			Auth
+//	@Success		204
+//	@Failure		400	{object}	object{err=string}
+//	@Failure		404	{object}	object{err=string}
+//	@Failure		429	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/confirm/resend [post]
+func (a *AuthHandler) ResendCode(w http.ResponseWriter, r *http.Request) {
+	var cr domain.ConfirmResend
+	err := json.NewDecoder(r.Body).Decode(&cr)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "ResendCode", err, "Failed to decode json from body")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "ResendCode")
+
+	code, err := generateRandomNumber()
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "ResendCode", err, "Failed to generate code")
+		return
+	}
+
+	cr.Email = strings.TrimSpace(cr.Email)
+	email, err := a.AuthUsecase.ResendCode(cr, code)
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "ResendCode", err, "Failed to save code")
+		return
+	}
+	if email == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	err = smtp.SendMailToClient("Код подтверждения", code, email)
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "ResendCode", err, "Failed to send code")
+		return
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -308,6 +460,146 @@ func (a *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
+// ForgotPassword godoc
+//
+//	@Summary		request password reset
+//	@Description	send single-use password reset link to user email
+//	@Tags			Auth
+//	@Accept			json
+//	@Param			body	body	domain.PasswordResetRequest	true	"user email"
+//	@Success		204
+//	@Failure		400	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/password/forgot [post]
+func (a *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req domain.PasswordResetRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "ForgotPassword", err, "Failed to decode json from body")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "ForgotPassword")
+
+	req.Email = strings.TrimSpace(req.Email)
+	if !valid(req.Email) {
+		domain.WriteError(w, domain.ErrBadRequest.Error(), http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "ForgotPassword", domain.ErrBadRequest, "email is invalid")
+		return
+	}
+
+	token, err := a.AuthUsecase.ForgotPassword(req.Email)
+	if errors.Is(err, domain.ErrNotFound) {
+		// answer as if the reset link was sent, or the form would tell
+		// which emails have an account
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "ForgotPassword", err, "Failed to create reset token")
+		return
+	}
+
+	// the answer must not wait for SMTP, an unknown email gets it at once
+	sendMailAsync("ForgotPassword", "Сброс пароля", "Для сброса пароля перейдите по ссылке: "+smtp.ResetPasswordLink(token), req.Email)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendMailAsync sends the mail in the background, so that the response time
+// does not depend on whether a mail was sent. Failures are only logged.
+func sendMailAsync(funcName, title, body, email string) {
+	go func() {
+		if err := smtp.SendMailToClient(title, body, email); err != nil {
+			logs.LogError(logs.Logger, "auth/http", funcName, err, "Failed to send mail")
+		}
+	}()
+}
+
+// ResetPassword godoc
+//
+//	@Summary		reset password
+//	@Description	set new password by reset token and revoke all user sessions
+//	@Tags			Auth
+//	@Accept			json
+//	@Param			body	body	domain.PasswordReset	true	"reset token and new password"
+//	@Success		204
+//	@Failure		400	{object}	object{err=string,violations=[]domain.PasswordViolation}
+//	@Failure		404	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/password/reset [post]
+func (a *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var reset domain.PasswordReset
+	err := json.NewDecoder(r.Body).Decode(&reset)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "ResetPassword", err, "Failed to decode json from body")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "ResetPassword")
+
+	if err = a.AuthUsecase.ResetPassword(reset); err != nil {
+		domain.WriteUsecaseError(w, err)
+		logs.LogError(logs.Logger, "auth/http", "ResetPassword", err, "Failed to reset password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangePassword godoc
+//
+//	@Summary		change password
+//	@Description	change password of current user and optionally revoke all other sessions, wrong current passwords count towards the login lockout
+//	@Tags			Auth
+//	@Accept			json
+//	@Param			body	body	domain.PasswordChange	true	"current and new password"
+//	@Success		204
+//	@Failure		400	{object}	object{err=string,violations=[]domain.PasswordViolation}
+//	@Failure		401	{object}	object{err=string}
+//	@Failure		423	{object}	object{err=string}
+//	@Failure		429	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/password [put]
+func (a *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "ChangePassword", err, err.Error())
+		return
+	}
+
+	var change domain.PasswordChange
+	err = json.NewDecoder(r.Body).Decode(&change)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "ChangePassword", err, "Failed to decode json from body")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "ChangePassword")
+
+	c, _ := r.Cookie("session_token")
+	err = a.AuthUsecase.ChangePassword(id, c.Value, change)
+	var blocked *domain.LoginBlockedError
+	if errors.As(err, &blocked) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		if blocked.Notify {
+			user, userErr := a.AuthUsecase.GetByID(id)
+			if userErr == nil {
+				a.sendLockoutMail(user.Email, blocked.RetryAfter)
+			}
+		}
+	}
+	if err != nil {
+		domain.WriteUsecaseError(w, err)
+		logs.LogError(logs.Logger, "auth/http", "ChangePassword", err, "Failed to change password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func generateRandomNumber() (string, error) {
 	const digits = "0123456789"
 	const length = 6
@@ -327,6 +619,72 @@ func generateRandomNumber() (string, error) {
 	return randomNumber, nil
 }
 
+const refreshCookiePath = "/api/v1/auth/refresh"
+
+// startSession puts the session into cookies and records the device it was
+// issued to.
+func (a *AuthHandler) startSession(w http.ResponseWriter, r *http.Request, session domain.Session) {
+	setSessionCookie(w, session)
+
+	if _, err := a.AuthUsecase.TouchSession(session.Token, domain.ClientIP(r), r.UserAgent()); err != nil {
+		logs.LogError(logs.Logger, "auth/http", "startSession", err, "Failed to record session metadata")
+	}
+}
+
+// setSessionCookie keeps the access token cookie for the whole session, the
+// client refreshes the token inside when the server rejects it as expired.
+// Only remember me sessions get persistent cookies, others end with the
+// browser.
+func setSessionCookie(w http.ResponseWriter, session domain.Session) {
+	var expires time.Time
+	if session.Policy == domain.RememberMeSessionPolicy {
+		expires = session.RefreshExpiresAt
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_token",
+		Value:    session.Token,
+		Expires:  expires,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Secure:   true,
+	})
+
+	if session.RefreshToken != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     "refresh_token",
+			Value:    session.RefreshToken,
+			Expires:  expires,
+			Path:     refreshCookiePath,
+			HttpOnly: true,
+			SameSite: http.SameSiteNoneMode,
+			Secure:   true,
+		})
+	}
+}
+
+func clearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_token",
+		Value:    "",
+		Expires:  time.Now(),
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Secure:   true,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		Expires:  time.Now(),
+		Path:     refreshCookiePath,
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Secure:   true,
+	})
+}
+
 func (a *AuthHandler) getUserID(r *http.Request) (int, error) {
 	c, err := r.Cookie("session_token")
 	if err != nil {
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+)
+
+// JWKS godoc
+//
+//	@Summary		public signing keys
+//	@Description	JSON Web Key Set with public keys used to verify access tokens
+//	@Tags			Auth
+//	@Produce		json
+//	@Success		200	{object}	domain.JWKS
+//	@Router			/.well-known/jwks.json [get]
+func (a *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if err := json.NewEncoder(w).Encode(a.AuthUsecase.JWKS()); err != nil {
+		logs.LogError(logs.Logger, "auth/http", "JWKS", err, "Failed to encode jwks")
+	}
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/auth/delivery/smtp"
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+)
+
+// magicLinkCookie binds the emailed link to the browser that requested it,
+// so a forwarded link is useless on its own.
+const magicLinkCookie = "magic_link_nonce"
+
+// RequestMagicLink godoc
+//
+//	@Summary		request magic link
+//	@Description	send single-use login link to user email, link works only in the same browser
+//	@Tags			Auth
+//	@Accept			json
+//	@Param			body	body	domain.MagicLinkRequest	true	"user email"
+//	@Success		204
+//	@Failure		400	{object}	object{err=string}
+//	@Failure		404	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/magic-link [post]
+func (a *AuthHandler) RequestMagicLink(w http.ResponseWriter, r *http.Request) {
+	var req domain.MagicLinkRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "RequestMagicLink", err, "Failed to decode json from body")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "RequestMagicLink")
+
+	req.Email = strings.TrimSpace(req.Email)
+	if !valid(req.Email) {
+		domain.WriteError(w, domain.ErrBadRequest.Error(), http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "RequestMagicLink", domain.ErrBadRequest, "email is invalid")
+		return
+	}
+
+	nonce, err := generateNonce()
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "RequestMagicLink", err, "Failed to generate nonce")
+		return
+	}
+
+	// the cookie is set whether the email is registered or not, its
+	// presence must not tell the two apart
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicLinkCookie,
+		Value:    nonce,
+		Path:     "/api/v1/auth/magic-link",
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Secure:   true,
+	})
+
+	token, err := a.AuthUsecase.RequestMagicLink(req.Email, nonce)
+	if errors.Is(err, domain.ErrNotFound) {
+		// no link is stored or mailed for an unknown email, the nonce in
+		// the cookie matches nothing
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "RequestMagicLink", err, "Failed to create magic link")
+		return
+	}
+
+	sendMailAsync("RequestMagicLink", "Вход в AtomHack", "Для входа перейдите по ссылке: "+smtp.MagicLink(token), req.Email)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoginMagicLink godoc
+//
+//	@Summary		login by magic link
+//	@Description	check magic link token against browser nonce and put session into cookie
+//	@Tags			Auth
+//	@Accept			json
+//	@Param			body	body		domain.MagicLinkLogin	true	"token from the link"
+//	@Success		200		{object}	object{body=object{id=int,mfaRequired=bool,challengeToken=string}}
+//	@Failure		400		{object}	object{err=string}
+//	@Failure		403		{object}	object{err=string}
+//	@Failure		404		{object}	object{err=string}
+//	@Failure		500		{object}	object{err=string}
+//	@Router			/api/v1/auth/magic-link/verify [post]
+func (a *AuthHandler) LoginMagicLink(w http.ResponseWriter, r *http.Request) {
+	c, err := r.Cookie(magicLinkCookie)
+	if err != nil {
+		domain.WriteError(w, domain.ErrInvalidToken.Error(), http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "LoginMagicLink", err, "Nonce cookie is missing")
+		return
+	}
+
+	var login domain.MagicLinkLogin
+	err = json.NewDecoder(r.Body).Decode(&login)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "LoginMagicLink", err, "Failed to decode json from body")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "LoginMagicLink")
+
+	session, userID, err := a.AuthUsecase.LoginMagicLink(login.Token, c.Value, login.RememberMe)
+	if errors.Is(err, domain.ErrMFARequired) {
+		domain.WriteResponse(
+			w,
+			map[string]interface{}{
+				"mfaRequired":    true,
+				"challengeToken": session.Token,
+			},
+			http.StatusOK,
+		)
+		return
+	}
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "LoginMagicLink", err, "Failed to login")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     magicLinkCookie,
+		Value:    "",
+		Expires:  time.Now(),
+		Path:     "/api/v1/auth/magic-link",
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Secure:   true,
+	})
+	a.startSession(w, r, session)
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"id": userID,
+		},
+		http.StatusOK,
+	)
+}
+
+func generateNonce() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
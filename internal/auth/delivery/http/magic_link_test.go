@@ -0,0 +1,77 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+)
+
+type magicLinkUsecase struct {
+	domain.AuthUsecase
+	known string
+}
+
+func (u *magicLinkUsecase) RequestMagicLink(email string, nonce string) (string, error) {
+	if email != u.known {
+		return "", domain.ErrNotFound
+	}
+
+	return "id.signature", nil
+}
+
+func requestMagicLink(t *testing.T, a *AuthHandler, email string) *http.Response {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/magic-link", strings.NewReader(`{"email":"`+email+`"}`))
+	w := httptest.NewRecorder()
+	a.RequestMagicLink(w, r)
+
+	return w.Result()
+}
+
+func TestRequestMagicLinkHidesUnknownEmail(t *testing.T) {
+	a := &AuthHandler{AuthUsecase: &magicLinkUsecase{known: "known@example.com"}}
+
+	known := requestMagicLink(t, a, "known@example.com")
+	unknown := requestMagicLink(t, a, "unknown@example.com")
+
+	if known.StatusCode != http.StatusNoContent || unknown.StatusCode != known.StatusCode {
+		t.Fatalf("status differs: known %d, unknown %d", known.StatusCode, unknown.StatusCode)
+	}
+
+	headerNames := func(h http.Header) []string {
+		var names []string
+		for name := range h {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	if !reflect.DeepEqual(headerNames(known.Header), headerNames(unknown.Header)) {
+		t.Fatalf("headers differ: known %v, unknown %v", known.Header, unknown.Header)
+	}
+
+	knownCookies, unknownCookies := known.Cookies(), unknown.Cookies()
+	if len(knownCookies) != 1 || len(unknownCookies) != 1 {
+		t.Fatalf("expected one cookie each: known %v, unknown %v", knownCookies, unknownCookies)
+	}
+
+	k, u := knownCookies[0], unknownCookies[0]
+	if k.Value == "" || u.Value == "" || k.Value == u.Value {
+		t.Fatalf("nonces must be fresh and non-empty: %q, %q", k.Value, u.Value)
+	}
+
+	// only the nonce may differ
+	k.Value, u.Value, k.Raw, u.Raw = "", "", "", ""
+	if !reflect.DeepEqual(k, u) {
+		t.Fatalf("cookies differ: known %+v, unknown %+v", k, u)
+	}
+	if k.Name != magicLinkCookie || !k.HttpOnly || !k.Secure || k.SameSite != http.SameSiteNoneMode {
+		t.Fatalf("unexpected cookie attributes: %+v", k)
+	}
+}
@@ -0,0 +1,253 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+)
+
+// LoginMFA godoc
+//
+//	@Summary		finish login with second factor
+//	@Description	check TOTP or recovery code for challenge token returned by login and put session into cookie
+//	@Tags			MFA
+//	@Accept			json
+//	@Param			body	body		domain.MFALogin	true	"challenge token and TOTP or recovery code"
+//	@Success		200		{object}	object{body=object{id=int}}
+//	@Failure		400		{object}	object{err=string}
+//	@Failure		404		{object}	object{err=string}
+//	@Failure		409		{object}	object{err=string}
+//	@Failure		500		{object}	object{err=string}
+//	@Router			/api/v1/auth/login/mfa [post]
+func (a *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var login domain.MFALogin
+	err := json.NewDecoder(r.Body).Decode(&login)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "LoginMFA", err, "Failed to decode json from body")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "LoginMFA")
+
+	session, userID, err := a.AuthUsecase.LoginMFA(login)
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "LoginMFA", err, "Failed to login")
+		return
+	}
+
+	a.startSession(w, r, session)
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"id": userID,
+		},
+		http.StatusOK,
+	)
+}
+
+// EnrollTOTP godoc
+//
+//	@Summary		start TOTP enrollment
+//	@Description	generate TOTP secret for current user, it is inactive until verified
+//	@Tags			MFA
+//	@Produce		json
+//	@Success		200	{object}	object{body=domain.TOTPEnrollment}
+//	@Failure		401	{object}	object{err=string}
+//	@Failure		409	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/mfa/totp/enroll [post]
+func (a *AuthHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "EnrollTOTP", err, err.Error())
+		return
+	}
+
+	enrollment, err := a.AuthUsecase.EnrollTOTP(id)
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "EnrollTOTP", err, "Failed to enroll TOTP")
+		return
+	}
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"secret": enrollment.Secret,
+			"uri":    enrollment.URI,
+		},
+		http.StatusOK,
+	)
+}
+
+// ActivateTOTP godoc
+//
+//	@Summary		activate TOTP
+//	@Description	verify first TOTP code, enable second factor for current user and return recovery codes
+//	@Tags			MFA
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.TOTPCode	true	"TOTP code"
+//	@Success		200		{object}	object{body=object{recoveryCodes=[]string}}
+//	@Failure		400		{object}	object{err=string}
+//	@Failure		401		{object}	object{err=string}
+//	@Failure		404		{object}	object{err=string}
+//	@Failure		500		{object}	object{err=string}
+//	@Router			/api/v1/auth/mfa/totp/verify [post]
+func (a *AuthHandler) ActivateTOTP(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "ActivateTOTP", err, err.Error())
+		return
+	}
+
+	var code domain.TOTPCode
+	err = json.NewDecoder(r.Body).Decode(&code)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "ActivateTOTP", err, "Failed to decode json from body")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "ActivateTOTP")
+
+	recoveryCodes, err := a.AuthUsecase.ActivateTOTP(id, code.Code)
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "ActivateTOTP", err, "Failed to activate TOTP")
+		return
+	}
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"recoveryCodes": recoveryCodes,
+		},
+		http.StatusOK,
+	)
+}
+
+// DisableTOTP godoc
+//
+//	@Summary		disable TOTP
+//	@Description	remove second factor of current user, requires valid TOTP code
+//	@Tags			MFA
+//	@Accept			json
+//	@Param			body	body	domain.TOTPCode	true	"TOTP code"
+//	@Success		204
+//	@Failure		400	{object}	object{err=string}
+//	@Failure		401	{object}	object{err=string}
+//	@Failure		404	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/mfa/totp [delete]
+func (a *AuthHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "DisableTOTP", err, err.Error())
+		return
+	}
+
+	var code domain.TOTPCode
+	err = json.NewDecoder(r.Body).Decode(&code)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "DisableTOTP", err, "Failed to decode json from body")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "DisableTOTP")
+
+	if err = a.AuthUsecase.DisableTOTP(id, code.Code); err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "DisableTOTP", err, "Failed to disable TOTP")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes godoc
+//
+//	@Summary		regenerate recovery codes
+//	@Description	invalidate old recovery codes and return new ones, requires valid TOTP code
+//	@Tags			MFA
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		domain.TOTPCode	true	"TOTP code"
+//	@Success		200		{object}	object{body=object{recoveryCodes=[]string}}
+//	@Failure		400		{object}	object{err=string}
+//	@Failure		401		{object}	object{err=string}
+//	@Failure		404		{object}	object{err=string}
+//	@Failure		500		{object}	object{err=string}
+//	@Router			/api/v1/auth/mfa/recovery-codes [post]
+func (a *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "RegenerateRecoveryCodes", err, err.Error())
+		return
+	}
+
+	var code domain.TOTPCode
+	err = json.NewDecoder(r.Body).Decode(&code)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "RegenerateRecoveryCodes", err, "Failed to decode json from body")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "RegenerateRecoveryCodes")
+
+	recoveryCodes, err := a.AuthUsecase.RegenerateRecoveryCodes(id, code.Code)
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "RegenerateRecoveryCodes", err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"recoveryCodes": recoveryCodes,
+		},
+		http.StatusOK,
+	)
+}
+
+// CountRecoveryCodes godoc
+//
+//	@Summary		count recovery codes
+//	@Description	return number of unused recovery codes of current user
+//	@Tags			MFA
+//	@Produce		json
+//	@Success		200	{object}	object{body=object{remaining=int}}
+//	@Failure		401	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/mfa/recovery-codes [get]
+func (a *AuthHandler) CountRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "CountRecoveryCodes", err, err.Error())
+		return
+	}
+
+	count, err := a.AuthUsecase.CountRecoveryCodes(id)
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "CountRecoveryCodes", err, "Failed to count recovery codes")
+		return
+	}
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"remaining": count,
+		},
+		http.StatusOK,
+	)
+}
@@ -0,0 +1,118 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+)
+
+// Introspect godoc
+//
+//	@Summary		introspect token
+//	@Description	RFC 7662 token introspection for other services, client authenticates with HTTP Basic or client_id and client_secret form fields
+//	@Tags			OAuth
+//	@Accept			x-www-form-urlencoded
+//	@Produce		json
+//	@Param			token			formData	string	true	"access token"
+//	@Param			token_type_hint	formData	string	false	"access_token"
+//	@Success		200				{object}	domain.TokenIntrospection
+//	@Failure		400				{object}	object{error=string}
+//	@Failure		401				{object}	object{error=string}
+//	@Failure		500				{object}	object{error=string}
+//	@Router			/oauth/introspect [post]
+func (a *AuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if !a.authenticateClient(w, r, "Introspect") {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	introspection, err := a.AuthUsecase.Introspect(token)
+	if err != nil {
+		writeOAuthError(w, "server_error", domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "Introspect", err, "Failed to introspect token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err = json.NewEncoder(w).Encode(introspection); err != nil {
+		logs.LogError(logs.Logger, "auth/http", "Introspect", err, "Failed to encode introspection")
+	}
+}
+
+// Revoke godoc
+//
+//	@Summary		revoke token
+//	@Description	RFC 7009 revocation of an access or refresh token, revoking either ends the session, unknown tokens are accepted
+//	@Tags			OAuth
+//	@Accept			x-www-form-urlencoded
+//	@Param			token			formData	string	true	"access or refresh token"
+//	@Param			token_type_hint	formData	string	false	"access_token or refresh_token"
+//	@Success		200
+//	@Failure		400	{object}	object{error=string}
+//	@Failure		401	{object}	object{error=string}
+//	@Failure		500	{object}	object{error=string}
+//	@Router			/oauth/revoke [post]
+func (a *AuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if !a.authenticateClient(w, r, "Revoke") {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	err := a.AuthUsecase.RevokeToken(token, r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		writeOAuthError(w, "server_error", domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "Revoke", err, "Failed to revoke token")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// authenticateClient parses the form and checks client credentials sent with
+// HTTP Basic or in the body, writing the RFC 6749 error on failure.
+func (a *AuthHandler) authenticateClient(w http.ResponseWriter, r *http.Request, funcName string) bool {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, "invalid_request", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", funcName, err, "Failed to parse form")
+		return false
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		// RFC 6749 2.3.1: credentials are form-urlencoded before Basic encoding
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	if err := a.AuthUsecase.AuthenticateClient(id, secret); err != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		writeOAuthError(w, "invalid_client", domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", funcName, err, "Client authentication failed")
+		return false
+	}
+
+	return true
+}
+
+func writeOAuthError(w http.ResponseWriter, code string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// GetSessions godoc
+//
+//	@Summary		list sessions
+//	@Description	list active sessions of current user with device metadata, newest first
+//	@Tags			Sessions
+//	@Produce		json
+//	@Success		200	{object}	object{body=object{sessions=[]domain.SessionInfo}}
+//	@Failure		401	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/sessions [get]
+func (a *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "GetSessions", err, err.Error())
+		return
+	}
+
+	c, _ := r.Cookie("session_token")
+	sessions, err := a.AuthUsecase.GetSessions(id, c.Value)
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "GetSessions", err, "Failed to get sessions")
+		return
+	}
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"sessions": sessions,
+		},
+		http.StatusOK,
+	)
+}
+
+// DeleteSession godoc
+//
+//	@Summary		revoke session
+//	@Description	log out one session of current user, cookies are cleared if it is the current one
+//	@Tags			Sessions
+//	@Param			id	path	string	true	"session id"
+//	@Success		204
+//	@Failure		401	{object}	object{err=string}
+//	@Failure		404	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/sessions/{id} [delete]
+func (a *AuthHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "DeleteSession", err, err.Error())
+		return
+	}
+
+	c, _ := r.Cookie("session_token")
+	if err = a.AuthUsecase.DeleteSession(id, mux.Vars(r)["id"]); err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "DeleteSession", err, "Failed to delete session")
+		return
+	}
+
+	if _, err = a.AuthUsecase.GetUserID(c.Value); err != nil {
+		clearSessionCookies(w)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteOtherSessions godoc
+//
+//	@Summary		log out everywhere else
+//	@Description	revoke all sessions of current user except the current one
+//	@Tags			Sessions
+//	@Success		204
+//	@Failure		401	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/sessions [delete]
+func (a *AuthHandler) DeleteOtherSessions(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "DeleteOtherSessions", err, err.Error())
+		return
+	}
+
+	c, _ := r.Cookie("session_token")
+	if err = a.AuthUsecase.DeleteOtherSessions(id, c.Value); err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "DeleteOtherSessions", err, "Failed to delete other sessions")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+
+	"github.com/go-webauthn/webauthn/protocol"
+)
+
+const webAuthnCookie = "webauthn_login"
+
+// BeginWebAuthnRegistration godoc
+//
+//	@Summary		begin passkey registration
+//	@Description	return credential creation options for navigator.credentials.create
+//	@Tags			WebAuthn
+//	@Produce		json
+//	@Success		200	{object}	object{body=object{publicKey=object}}
+//	@Failure		401	{object}	object{err=string}
+//	@Failure		404	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/webauthn/register/begin [post]
+func (a *AuthHandler) BeginWebAuthnRegistration(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "BeginWebAuthnRegistration", err, err.Error())
+		return
+	}
+
+	creation, err := a.AuthUsecase.BeginWebAuthnRegistration(id)
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "BeginWebAuthnRegistration", err, "Failed to begin registration")
+		return
+	}
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"publicKey": creation.Response,
+		},
+		http.StatusOK,
+	)
+}
+
+// FinishWebAuthnRegistration godoc
+//
+//	@Summary		finish passkey registration
+//	@Description	verify attestation returned by navigator.credentials.create and save credential
+//	@Tags			WebAuthn
+//	@Accept			json
+//	@Param			body	body	object	true	"PublicKeyCredential with attestation response"
+//	@Success		204
+//	@Failure		400	{object}	object{err=string}
+//	@Failure		401	{object}	object{err=string}
+//	@Failure		404	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/webauthn/register/finish [post]
+func (a *AuthHandler) FinishWebAuthnRegistration(w http.ResponseWriter, r *http.Request) {
+	id, err := a.getUserID(r)
+	if id == 0 {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "FinishWebAuthnRegistration", err, err.Error())
+		return
+	}
+
+	response, err := protocol.ParseCredentialCreationResponseBody(r.Body)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "FinishWebAuthnRegistration", err, "Failed to parse attestation")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "FinishWebAuthnRegistration")
+
+	if err = a.AuthUsecase.FinishWebAuthnRegistration(id, response); err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "FinishWebAuthnRegistration", err, "Failed to finish registration")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BeginWebAuthnLogin godoc
+//
+//	@Summary		begin passkey login
+//	@Description	return credential request options for navigator.credentials.get
+//	@Tags			WebAuthn
+//	@Produce		json
+//	@Success		200	{object}	object{body=object{publicKey=object}}
+//	@Failure		404	{object}	object{err=string}
+//	@Failure		500	{object}	object{err=string}
+//	@Router			/api/v1/auth/webauthn/login/begin [post]
+func (a *AuthHandler) BeginWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	assertion, key, err := a.AuthUsecase.BeginWebAuthnLogin()
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "BeginWebAuthnLogin", err, "Failed to begin login")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     webAuthnCookie,
+		Value:    key,
+		Path:     "/api/v1/auth/webauthn",
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Secure:   true,
+	})
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"publicKey": assertion.Response,
+		},
+		http.StatusOK,
+	)
+}
+
+// FinishWebAuthnLogin godoc
+//
+//	@Summary		finish passkey login
+//	@Description	verify assertion returned by navigator.credentials.get and put session into cookie
+//	@Tags			WebAuthn
+//	@Accept			json
+//	@Param			body		body		object	true	"PublicKeyCredential with assertion response"
+//	@Param			rememberMe	query		bool	false	"keep session after browser is closed"
+//	@Success		200			{object}	object{body=object{id=int}}
+//	@Failure		400			{object}	object{err=string}
+//	@Failure		403			{object}	object{err=string}
+//	@Failure		404			{object}	object{err=string}
+//	@Failure		500			{object}	object{err=string}
+//	@Router			/api/v1/auth/webauthn/login/finish [post]
+func (a *AuthHandler) FinishWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	c, err := r.Cookie(webAuthnCookie)
+	if err != nil {
+		domain.WriteError(w, domain.ErrInvalidToken.Error(), http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "FinishWebAuthnLogin", err, "WebAuthn cookie is missing")
+		return
+	}
+
+	response, err := protocol.ParseCredentialRequestResponseBody(r.Body)
+	if err != nil {
+		domain.WriteError(w, "somethings wrong with JSON", http.StatusBadRequest)
+		logs.LogError(logs.Logger, "auth/http", "FinishWebAuthnLogin", err, "Failed to parse assertion")
+		return
+	}
+	defer domain.CloseAndAlert(r.Body, "auth/http", "FinishWebAuthnLogin")
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     webAuthnCookie,
+		Value:    "",
+		Expires:  time.Now(),
+		Path:     "/api/v1/auth/webauthn",
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Secure:   true,
+	})
+
+	rememberMe, _ := strconv.ParseBool(r.URL.Query().Get("rememberMe"))
+	session, userID, err := a.AuthUsecase.FinishWebAuthnLogin(c.Value, response, rememberMe)
+	if err != nil {
+		domain.WriteError(w, err.Error(), domain.GetStatusCode(err))
+		logs.LogError(logs.Logger, "auth/http", "FinishWebAuthnLogin", err, "Failed to login")
+		return
+	}
+
+	a.startSession(w, r, session)
+
+	domain.WriteResponse(
+		w,
+		map[string]interface{}{
+			"id": userID,
+		},
+		http.StatusOK,
+	)
+}
@@ -3,6 +3,7 @@ package smtp
 import (
 	"fmt"
 	"net/smtp"
+	"net/url"
 	"os"
 	"strconv"
 
@@ -73,6 +74,14 @@ func SendMailToClient(title, body string, clientEmail string) error {
 	return nil
 }
 
+func ResetPasswordLink(token string) string {
+	return os.Getenv("PASSWORD_RESET_URL") + "?token=" + url.QueryEscape(token)
+}
+
+func MagicLink(token string) string {
+	return os.Getenv("MAGIC_LINK_URL") + "?token=" + url.QueryEscape(token)
+}
+
 func customAuth(username, password, host string) smtp.Auth {
 	return &loginAuth{username, password, host}
 }
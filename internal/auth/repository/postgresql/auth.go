@@ -39,6 +39,18 @@ const userExistsQuery = `
 				  WHERE email = $1)
 `
 
+const getPasswordByIdQuery = `
+	SELECT password
+	FROM "user"
+	WHERE id = $1
+`
+
+const updatePasswordQuery = `
+	UPDATE "user"
+	SET password = $2
+	WHERE id = $1
+`
+
 type authPostgresqlRepository struct {
 	db  domain.PgxPoolIface
 	ctx context.Context
@@ -166,3 +178,37 @@ func (r *authPostgresqlRepository) ConfirmUser(id int) (string, error) {
 	}
 	return email, nil
 }
+
+func (r *authPostgresqlRepository) GetPasswordByID(id int) ([]byte, error) {
+	result := r.db.QueryRow(r.ctx, getPasswordByIdQuery, id)
+
+	var password []byte
+	err := result.Scan(&password)
+	if err == pgx.ErrNoRows {
+		logs.LogError(logs.Logger, "auth/postgres", "GetPasswordByID", err, err.Error())
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "GetPasswordByID", err, err.Error())
+		return nil, err
+	}
+
+	return password, nil
+}
+
+func (r *authPostgresqlRepository) UpdatePassword(id int, password []byte) error {
+	if id == 0 || len(password) == 0 {
+		return domain.ErrBadRequest
+	}
+
+	tag, err := r.db.Exec(r.ctx, updatePasswordQuery, id, password)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "UpdatePassword", err, err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
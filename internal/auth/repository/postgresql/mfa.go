@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const getTOTPQuery = `
+	SELECT user_id, secret, enabled
+	FROM user_totp
+	WHERE user_id = $1
+`
+
+const setTOTPSecretQuery = `
+	INSERT INTO user_totp (user_id, secret, enabled)
+	VALUES ($1, $2, false)
+	ON CONFLICT (user_id) DO UPDATE
+	SET secret = EXCLUDED.secret, enabled = false
+`
+
+const enableTOTPQuery = `
+	UPDATE user_totp
+	SET enabled = true
+	WHERE user_id = $1
+`
+
+const deleteTOTPQuery = `
+	DELETE FROM user_totp
+	WHERE user_id = $1
+`
+
+const deleteRecoveryCodesQuery = `
+	DELETE FROM user_recovery_code
+	WHERE user_id = $1
+`
+
+const addRecoveryCodeQuery = `
+	INSERT INTO user_recovery_code (user_id, lookup, code_hash)
+	VALUES ($1, $2, $3)
+`
+
+const getRecoveryCodesQuery = `
+	SELECT id, lookup, code_hash
+	FROM user_recovery_code
+	WHERE user_id = $1 AND lookup = $2 AND used_at IS NULL
+`
+
+const useRecoveryCodeQuery = `
+	UPDATE user_recovery_code
+	SET used_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND used_at IS NULL
+`
+
+const countRecoveryCodesQuery = `
+	SELECT COUNT(*)
+	FROM user_recovery_code
+	WHERE user_id = $1 AND used_at IS NULL
+`
+
+func (r *authPostgresqlRepository) GetTOTP(userID int) (domain.TOTP, error) {
+	result := r.db.QueryRow(r.ctx, getTOTPQuery, userID)
+
+	var totp domain.TOTP
+	err := result.Scan(&totp.UserID, &totp.Secret, &totp.Enabled)
+	if err == pgx.ErrNoRows {
+		return domain.TOTP{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "GetTOTP", err, err.Error())
+		return domain.TOTP{}, err
+	}
+
+	return totp, nil
+}
+
+func (r *authPostgresqlRepository) SetTOTPSecret(userID int, secret []byte) error {
+	if userID == 0 || len(secret) == 0 {
+		return domain.ErrBadRequest
+	}
+
+	_, err := r.db.Exec(r.ctx, setTOTPSecretQuery, userID, secret)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "SetTOTPSecret", err, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *authPostgresqlRepository) EnableTOTP(userID int) error {
+	tag, err := r.db.Exec(r.ctx, enableTOTPQuery, userID)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "EnableTOTP", err, err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *authPostgresqlRepository) DeleteTOTP(userID int) error {
+	_, err := r.db.Exec(r.ctx, deleteTOTPQuery, userID)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "DeleteTOTP", err, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *authPostgresqlRepository) ReplaceRecoveryCodes(userID int, codes []domain.RecoveryCode) error {
+	if userID == 0 {
+		return domain.ErrBadRequest
+	}
+
+	tx, err := r.db.Begin(r.ctx)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "ReplaceRecoveryCodes", err, err.Error())
+		return err
+	}
+	defer tx.Rollback(r.ctx)
+
+	if _, err = tx.Exec(r.ctx, deleteRecoveryCodesQuery, userID); err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "ReplaceRecoveryCodes", err, err.Error())
+		return err
+	}
+
+	for _, code := range codes {
+		if _, err = tx.Exec(r.ctx, addRecoveryCodeQuery, userID, code.Lookup, code.Hash); err != nil {
+			logs.LogError(logs.Logger, "auth/postgres", "ReplaceRecoveryCodes", err, err.Error())
+			return err
+		}
+	}
+
+	if err = tx.Commit(r.ctx); err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "ReplaceRecoveryCodes", err, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *authPostgresqlRepository) GetRecoveryCodes(userID int, lookup string) ([]domain.RecoveryCode, error) {
+	rows, err := r.db.Query(r.ctx, getRecoveryCodesQuery, userID, lookup)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "GetRecoveryCodes", err, err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []domain.RecoveryCode
+	for rows.Next() {
+		var code domain.RecoveryCode
+		if err = rows.Scan(&code.ID, &code.Lookup, &code.Hash); err != nil {
+			logs.LogError(logs.Logger, "auth/postgres", "GetRecoveryCodes", err, err.Error())
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "GetRecoveryCodes", err, err.Error())
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (r *authPostgresqlRepository) UseRecoveryCode(id int) (bool, error) {
+	tag, err := r.db.Exec(r.ctx, useRecoveryCodeQuery, id)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "UseRecoveryCode", err, err.Error())
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *authPostgresqlRepository) CountRecoveryCodes(userID int) (int, error) {
+	result := r.db.QueryRow(r.ctx, countRecoveryCodesQuery, userID)
+
+	var count int
+	if err := result.Scan(&count); err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "CountRecoveryCodes", err, err.Error())
+		return 0, err
+	}
+
+	return count, nil
+}
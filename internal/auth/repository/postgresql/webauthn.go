@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const addWebAuthnCredentialQuery = `
+	INSERT INTO user_webauthn_credential (credential_id, user_id, public_key, attestation_type, aaguid,
+	                                      sign_count, transports, backup_eligible, backup_state)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+`
+
+const getWebAuthnCredentialsQuery = `
+	SELECT credential_id, user_id, public_key, attestation_type, aaguid,
+	       sign_count, transports, backup_eligible, backup_state
+	FROM user_webauthn_credential
+	WHERE user_id = $1
+`
+
+const getWebAuthnCredentialOwnerQuery = `
+	SELECT user_id
+	FROM user_webauthn_credential
+	WHERE credential_id = $1
+`
+
+const updateWebAuthnCredentialQuery = `
+	UPDATE user_webauthn_credential
+	SET sign_count = $2, backup_state = $3
+	WHERE credential_id = $1
+`
+
+func (r *authPostgresqlRepository) AddWebAuthnCredential(cred domain.WebAuthnCredential) error {
+	if cred.UserID == 0 || len(cred.ID) == 0 || len(cred.PublicKey) == 0 {
+		return domain.ErrBadRequest
+	}
+
+	transports := cred.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+
+	_, err := r.db.Exec(r.ctx, addWebAuthnCredentialQuery,
+		cred.ID,
+		cred.UserID,
+		cred.PublicKey,
+		cred.AttestationType,
+		cred.AAGUID,
+		int64(cred.SignCount),
+		transports,
+		cred.BackupEligible,
+		cred.BackupState,
+	)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "AddWebAuthnCredential", err, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (r *authPostgresqlRepository) GetWebAuthnCredentials(userID int) ([]domain.WebAuthnCredential, error) {
+	rows, err := r.db.Query(r.ctx, getWebAuthnCredentialsQuery, userID)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "GetWebAuthnCredentials", err, err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var creds []domain.WebAuthnCredential
+	for rows.Next() {
+		var cred domain.WebAuthnCredential
+		var signCount int64
+		err = rows.Scan(
+			&cred.ID,
+			&cred.UserID,
+			&cred.PublicKey,
+			&cred.AttestationType,
+			&cred.AAGUID,
+			&signCount,
+			&cred.Transports,
+			&cred.BackupEligible,
+			&cred.BackupState,
+		)
+		if err != nil {
+			logs.LogError(logs.Logger, "auth/postgres", "GetWebAuthnCredentials", err, err.Error())
+			return nil, err
+		}
+		cred.SignCount = uint32(signCount)
+		creds = append(creds, cred)
+	}
+
+	if err = rows.Err(); err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "GetWebAuthnCredentials", err, err.Error())
+		return nil, err
+	}
+
+	return creds, nil
+}
+
+func (r *authPostgresqlRepository) GetWebAuthnCredentialOwner(credentialID []byte) (int, error) {
+	result := r.db.QueryRow(r.ctx, getWebAuthnCredentialOwnerQuery, credentialID)
+
+	var userID int
+	err := result.Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return 0, domain.ErrNotFound
+	}
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "GetWebAuthnCredentialOwner", err, err.Error())
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+func (r *authPostgresqlRepository) UpdateWebAuthnCredential(cred domain.WebAuthnCredential) error {
+	tag, err := r.db.Exec(r.ctx, updateWebAuthnCredentialQuery, cred.ID, int64(cred.SignCount), cred.BackupState)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/postgres", "UpdateWebAuthnCredential", err, err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"github.com/certified-juniors/AtomHack/internal/domain"
 	"strconv"
 	"time"
@@ -9,6 +10,18 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// Every key has a prefix naming its kind, session keys included, so that a
+// counter or a code key can never be passed off as a session token.
+const (
+	sessionPrefix         = "session:"
+	confirmCodePrefix     = "confirm_code:"
+	confirmAttemptsPrefix = "confirm_attempts:"
+	confirmCooldownPrefix = "confirm_cooldown:"
+	userSessionsPrefix    = "user_sessions:"
+	resetTokenPrefix      = "reset:"
+	userResetPrefix       = "user_reset:"
+)
+
 type sessionRedisRepository struct {
 	client *redis.Client
 }
@@ -23,7 +36,13 @@ func (s *sessionRedisRepository) Add(session domain.Session) error {
 	}
 
 	duration := session.ExpiresAt.Sub(time.Now())
-	err := s.client.Set(context.TODO(), session.Token, session.UserID, duration).Err()
+	userKey := userSessionsPrefix + strconv.Itoa(session.UserID)
+	_, err := s.client.TxPipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		pipe.Set(context.TODO(), sessionPrefix+session.Token, session.UserID, duration)
+		pipe.SAdd(context.TODO(), userKey, session.Token)
+		pipe.Expire(context.TODO(), userKey, duration)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -31,12 +50,17 @@ func (s *sessionRedisRepository) Add(session domain.Session) error {
 	return nil
 }
 
-func (s *sessionRedisRepository) AddCodeByID(id int, code string) error {
+func (s *sessionRedisRepository) AddCodeByID(id int, code string, ttl time.Duration) error {
 	if code == "" || id <= 0 {
 		return domain.ErrBadRequest
 	}
 
-	err := s.client.Set(context.TODO(), strconv.Itoa(id), code, 24*time.Hour).Err()
+	strID := strconv.Itoa(id)
+	_, err := s.client.TxPipelined(context.TODO(), func(pipe redis.Pipeliner) error {
+		pipe.Set(context.TODO(), confirmCodePrefix+strID, code, ttl)
+		pipe.Del(context.TODO(), confirmAttemptsPrefix+strID)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -44,12 +68,106 @@ func (s *sessionRedisRepository) AddCodeByID(id int, code string) error {
 	return nil
 }
 
+func (s *sessionRedisRepository) DeleteCodeByID(id int) error {
+	if id <= 0 {
+		return domain.ErrBadRequest
+	}
+
+	strID := strconv.Itoa(id)
+	err := s.client.Del(context.Background(), confirmCodePrefix+strID, confirmAttemptsPrefix+strID).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRedisRepository) IncrCodeAttempts(id int, ttl time.Duration) (int, error) {
+	if id <= 0 {
+		return 0, domain.ErrBadRequest
+	}
+
+	key := confirmAttemptsPrefix + strconv.Itoa(id)
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(context.Background(), key)
+		pipe.Expire(context.Background(), key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(incr.Val()), nil
+}
+
+func (s *sessionRedisRepository) SetCodeCooldown(id int, cooldown time.Duration) (bool, error) {
+	if id <= 0 {
+		return false, domain.ErrBadRequest
+	}
+
+	ok, err := s.client.SetNX(context.Background(), confirmCooldownPrefix+strconv.Itoa(id), 1, cooldown).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
+
 func (s *sessionRedisRepository) DeleteByToken(token string) error {
 	if token == "" {
 		return domain.ErrInvalidToken
 	}
 
-	err := s.client.Del(context.Background(), token).Err()
+	id, err := s.client.Get(context.Background(), sessionPrefix+token).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	family, err := s.familyByAccess(token)
+	if err != nil {
+		return err
+	}
+	if family != "" {
+		return s.RevokeRefreshFamily(family)
+	}
+
+	_, err = s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Del(context.Background(), sessionPrefix+token)
+		pipe.SRem(context.Background(), userSessionsPrefix+id, token)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRedisRepository) DeleteByUserID(id int) error {
+	if id <= 0 {
+		return domain.ErrBadRequest
+	}
+
+	strID := strconv.Itoa(id)
+	if err := s.revokeUserFamilies(strID, ""); err != nil {
+		return err
+	}
+
+	userKey := userSessionsPrefix + strID
+	tokens, err := s.client.SMembers(context.Background(), userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(tokens)+1)
+	for _, t := range tokens {
+		keys = append(keys, sessionPrefix+t)
+	}
+	err = s.client.Del(context.Background(), append(keys, userKey)...).Err()
 	if err != nil {
 		return err
 	}
@@ -62,7 +180,10 @@ func (s *sessionRedisRepository) GetUserID(token string) (string, error) {
 		return "", domain.ErrInvalidToken
 	}
 
-	id, err := s.client.Get(context.Background(), token).Result()
+	id, err := s.client.Get(context.Background(), sessionPrefix+token).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", domain.ErrUnauthorized
+	}
 	if err != nil {
 		return "", err
 	}
@@ -75,10 +196,123 @@ func (s *sessionRedisRepository) GetCodeByID(id string) (string, error) {
 		return "", domain.ErrInvalidToken
 	}
 
-	id, err := s.client.Get(context.Background(), id).Result()
+	code, err := s.client.Get(context.Background(), confirmCodePrefix+id).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", domain.ErrInvalidCode
+	}
 	if err != nil {
 		return "", err
 	}
 
+	return code, nil
+}
+
+func (s *sessionRedisRepository) DeleteOtherSessions(id int, token string) error {
+	if id <= 0 || token == "" {
+		return domain.ErrBadRequest
+	}
+
+	strID := strconv.Itoa(id)
+	keep, err := s.familyByAccess(token)
+	if err != nil {
+		return err
+	}
+	if err = s.revokeUserFamilies(strID, keep); err != nil {
+		return err
+	}
+
+	userKey := userSessionsPrefix + strID
+	tokens, err := s.client.SMembers(context.Background(), userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, t := range tokens {
+			if t == token {
+				continue
+			}
+			pipe.Del(context.Background(), sessionPrefix+t)
+			pipe.SRem(context.Background(), userKey, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRedisRepository) AddResetToken(token string, userID int, ttl time.Duration) error {
+	if token == "" || userID <= 0 {
+		return domain.ErrBadRequest
+	}
+
+	userKey := userResetPrefix + strconv.Itoa(userID)
+	prev, err := s.client.Get(context.Background(), userKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		if prev != "" {
+			pipe.Del(context.Background(), resetTokenPrefix+prev)
+		}
+		pipe.Set(context.Background(), resetTokenPrefix+token, userID, ttl)
+		pipe.Set(context.Background(), userKey, token, ttl)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetResetToken returns the user of a reset token without consuming it.
+func (s *sessionRedisRepository) GetResetToken(token string) (int, error) {
+	if token == "" {
+		return 0, domain.ErrInvalidToken
+	}
+
+	strID, err := s.client.Get(context.Background(), resetTokenPrefix+token).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.Atoi(strID)
+	if err != nil {
+		return 0, domain.ErrInvalidToken
+	}
+
+	return id, nil
+}
+
+func (s *sessionRedisRepository) ConsumeResetToken(token string) (int, error) {
+	if token == "" {
+		return 0, domain.ErrInvalidToken
+	}
+
+	strID, err := s.client.GetDel(context.Background(), resetTokenPrefix+token).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err = s.client.Del(context.Background(), userResetPrefix+strID).Err(); err != nil {
+		return 0, err
+	}
+
+	id, err := strconv.Atoi(strID)
+	if err != nil {
+		return 0, domain.ErrInvalidToken
+	}
+
 	return id, nil
 }
@@ -0,0 +1,140 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Failed logins are counted per email and per client IP. Accounts are keyed
+// by the email that was tried rather than the user ID, so an unregistered
+// address is paused and locked out exactly like a registered one. A block key
+// stops further attempts until it expires, its value tells a lockout from a
+// delay.
+const (
+	loginFailuresPrefix = "login_failures:"
+	loginBlockPrefix    = "login_block:"
+	loginLocked         = "locked"
+	loginDelayed        = "delayed"
+)
+
+func accountKey(email string) string {
+	return "email:" + email
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// RegisterLoginFailure counts a failed attempt, an empty email counts only
+// the IP.
+func (s *sessionRedisRepository) RegisterLoginFailure(email string, ip string, window time.Duration) (int, int, error) {
+	var account, byIP *redis.IntCmd
+	_, err := s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		if email != "" {
+			account = pipe.Incr(context.Background(), loginFailuresPrefix+accountKey(email))
+			pipe.Expire(context.Background(), loginFailuresPrefix+accountKey(email), window)
+		}
+		if ip != "" {
+			byIP = pipe.Incr(context.Background(), loginFailuresPrefix+ipKey(ip))
+			pipe.Expire(context.Background(), loginFailuresPrefix+ipKey(ip), window)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var accountFailures, ipFailures int
+	if account != nil {
+		accountFailures = int(account.Val())
+	}
+	if byIP != nil {
+		ipFailures = int(byIP.Val())
+	}
+
+	return accountFailures, ipFailures, nil
+}
+
+func (s *sessionRedisRepository) BlockLogin(email string, ip string, account domain.LoginBlock, byIP domain.LoginBlock) error {
+	_, err := s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		if email != "" && account.RetryAfter > 0 {
+			pipe.Set(context.Background(), loginBlockPrefix+accountKey(email), blockValue(account), account.RetryAfter)
+		}
+		if ip != "" && byIP.RetryAfter > 0 {
+			pipe.Set(context.Background(), loginBlockPrefix+ipKey(ip), blockValue(byIP), byIP.RetryAfter)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetLoginBlock returns the longest of the account and IP blocks, a lockout
+// wins over a delay.
+func (s *sessionRedisRepository) GetLoginBlock(email string, ip string) (domain.LoginBlock, error) {
+	keys := make([]string, 0, 2)
+	if email != "" {
+		keys = append(keys, loginBlockPrefix+accountKey(email))
+	}
+	if ip != "" {
+		keys = append(keys, loginBlockPrefix+ipKey(ip))
+	}
+
+	var block domain.LoginBlock
+	for _, key := range keys {
+		value, err := s.client.Get(context.Background(), key).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return domain.LoginBlock{}, err
+		}
+
+		ttl, err := s.client.PTTL(context.Background(), key).Result()
+		if err != nil {
+			return domain.LoginBlock{}, err
+		}
+		if ttl <= 0 {
+			continue
+		}
+
+		locked := value == loginLocked
+		if (locked && !block.Locked) || (locked == block.Locked && ttl > block.RetryAfter) {
+			block = domain.LoginBlock{Locked: locked, RetryAfter: ttl}
+		}
+	}
+
+	return block, nil
+}
+
+func (s *sessionRedisRepository) ResetLoginFailures(email string) error {
+	if email == "" {
+		return domain.ErrBadRequest
+	}
+
+	err := s.client.Del(context.Background(),
+		loginFailuresPrefix+accountKey(email),
+		loginBlockPrefix+accountKey(email),
+	).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func blockValue(block domain.LoginBlock) string {
+	if block.Locked {
+		return loginLocked
+	}
+
+	return loginDelayed
+}
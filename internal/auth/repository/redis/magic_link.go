@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const magicLinkPrefix = "magic_link:"
+
+func (s *sessionRedisRepository) AddMagicLink(id string, userID int, nonceHash string, ttl time.Duration) error {
+	if id == "" || userID <= 0 || nonceHash == "" {
+		return domain.ErrBadRequest
+	}
+
+	value := strconv.Itoa(userID) + ":" + nonceHash
+	err := s.client.Set(context.Background(), magicLinkPrefix+id, value, ttl).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRedisRepository) ConsumeMagicLink(id string) (int, string, error) {
+	if id == "" {
+		return 0, "", domain.ErrInvalidToken
+	}
+
+	value, err := s.client.GetDel(context.Background(), magicLinkPrefix+id).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, "", domain.ErrInvalidToken
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	strID, nonceHash, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, "", domain.ErrInvalidToken
+	}
+
+	userID, err := strconv.Atoi(strID)
+	if err != nil {
+		return 0, "", domain.ErrInvalidToken
+	}
+
+	return userID, nonceHash, nil
+}
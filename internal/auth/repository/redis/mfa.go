@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	mfaChallengePrefix = "mfa_challenge:"
+	mfaAttemptsPrefix  = "mfa_attempts:"
+	totpUsedPrefix     = "totp_used:"
+)
+
+func (s *sessionRedisRepository) AddMFAChallenge(token string, challenge domain.MFAChallenge, ttl time.Duration) error {
+	if token == "" || challenge.UserID <= 0 {
+		return domain.ErrBadRequest
+	}
+
+	key := mfaChallengePrefix + token
+	_, err := s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.HSet(context.Background(), key,
+			"user_id", challenge.UserID,
+			"policy", string(challenge.Policy),
+		)
+		pipe.Expire(context.Background(), key, ttl)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRedisRepository) GetMFAChallenge(token string) (domain.MFAChallenge, error) {
+	if token == "" {
+		return domain.MFAChallenge{}, domain.ErrInvalidToken
+	}
+
+	values, err := s.client.HGetAll(context.Background(), mfaChallengePrefix+token).Result()
+	if err != nil {
+		return domain.MFAChallenge{}, err
+	}
+	if len(values) == 0 {
+		return domain.MFAChallenge{}, domain.ErrInvalidToken
+	}
+
+	id, err := strconv.Atoi(values["user_id"])
+	if err != nil {
+		return domain.MFAChallenge{}, domain.ErrInvalidToken
+	}
+
+	return domain.MFAChallenge{
+		UserID: id,
+		Policy: domain.SessionPolicy(values["policy"]),
+	}, nil
+}
+
+func (s *sessionRedisRepository) DeleteMFAChallenge(token string) error {
+	if token == "" {
+		return domain.ErrInvalidToken
+	}
+
+	err := s.client.Del(context.Background(), mfaChallengePrefix+token, mfaAttemptsPrefix+token).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRedisRepository) IncrMFAChallengeAttempts(token string, ttl time.Duration) (int, error) {
+	if token == "" {
+		return 0, domain.ErrInvalidToken
+	}
+
+	key := mfaAttemptsPrefix + token
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(context.Background(), key)
+		pipe.Expire(context.Background(), key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(incr.Val()), nil
+}
+
+func (s *sessionRedisRepository) MarkTOTPUsed(userID int, step int64, ttl time.Duration) (bool, error) {
+	if userID <= 0 {
+		return false, domain.ErrBadRequest
+	}
+
+	key := totpUsedPrefix + strconv.Itoa(userID) + ":" + strconv.FormatInt(step, 10)
+	ok, err := s.client.SetNX(context.Background(), key, 1, ttl).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return ok, nil
+}
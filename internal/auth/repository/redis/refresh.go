@@ -0,0 +1,235 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Every login starts a refresh token family. The family hash keeps the
+// currently valid refresh and access tokens. Refresh tokens are stored only
+// as their SHA-256, and the records of rotated ones are kept until they
+// expire so that their reuse can be detected.
+// Families of a user are indexed in a sorted set scored by creation time in
+// milliseconds, so the oldest session is always the first one.
+const (
+	refreshTokenPrefix  = "refresh:"
+	refreshFamilyPrefix = "refresh_family:"
+	userFamiliesPrefix  = "user_families:"
+	accessFamilyPrefix  = "access_family:"
+)
+
+// useRefreshToken marks a refresh token as used and returns the use count
+// with the record, nil when the token does not exist. Checking and counting
+// in one script keeps concurrent refreshes from both seeing the first use,
+// and never recreates an expired record.
+var useRefreshToken = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return false
+end
+local used = redis.call('HINCRBY', KEYS[1], 'used', 1)
+return {used, redis.call('HGETALL', KEYS[1])}
+`)
+
+// hashRefreshToken is the form a refresh token is stored in.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *sessionRedisRepository) AddRefreshToken(rt domain.RefreshToken) error {
+	if rt.Token == "" || rt.Family == "" || rt.UserID <= 0 {
+		return domain.ErrBadRequest
+	}
+
+	strID := strconv.Itoa(rt.UserID)
+	familyKey := refreshFamilyPrefix + rt.Family
+	prevAccess, err := s.client.HGet(context.Background(), familyKey, "access").Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	userKey := userFamiliesPrefix + strID
+	ttl, err := s.client.TTL(context.Background(), userKey).Result()
+	if err != nil {
+		return err
+	}
+
+	hash := hashRefreshToken(rt.Token)
+	_, err = s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.HSet(context.Background(), refreshTokenPrefix+hash,
+			"user_id", rt.UserID,
+			"family", rt.Family,
+			"expires_at", rt.SessionExpiresAt.Unix(),
+			"policy", string(rt.Policy),
+			"used", 0,
+		)
+		pipe.ExpireAt(context.Background(), refreshTokenPrefix+hash, rt.ExpiresAt)
+		pipe.HSet(context.Background(), familyKey,
+			"user_id", rt.UserID,
+			"refresh", hash,
+			"access", rt.AccessToken,
+		)
+		pipe.HSetNX(context.Background(), familyKey, "created_at", time.Now().Unix())
+		pipe.HSetNX(context.Background(), familyKey, "expires_at", rt.SessionExpiresAt.Unix())
+		pipe.HSetNX(context.Background(), familyKey, "policy", string(rt.Policy))
+		pipe.HSetNX(context.Background(), familyKey, "idle", int64(rt.IdleTimeout/time.Second))
+		pipe.ExpireAt(context.Background(), familyKey, rt.ExpiresAt)
+		pipe.Set(context.Background(), accessFamilyPrefix+rt.AccessToken, rt.Family, 0)
+		pipe.ExpireAt(context.Background(), accessFamilyPrefix+rt.AccessToken, rt.ExpiresAt)
+		pipe.ZAddNX(context.Background(), userKey, redis.Z{Score: float64(time.Now().UnixMilli()), Member: rt.Family})
+		if time.Now().Add(ttl).Before(rt.ExpiresAt) {
+			pipe.ExpireAt(context.Background(), userKey, rt.ExpiresAt)
+		}
+		if prevAccess != "" && prevAccess != rt.AccessToken {
+			pipe.Del(context.Background(), sessionPrefix+prevAccess, accessFamilyPrefix+prevAccess)
+			pipe.SRem(context.Background(), userSessionsPrefix+strID, prevAccess)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRedisRepository) UseRefreshToken(token string) (domain.RefreshToken, bool, error) {
+	if token == "" {
+		return domain.RefreshToken{}, false, domain.ErrInvalidToken
+	}
+
+	result, err := useRefreshToken.Run(context.Background(), s.client, []string{refreshTokenPrefix + hashRefreshToken(token)}).Slice()
+	if errors.Is(err, redis.Nil) {
+		return domain.RefreshToken{}, false, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return domain.RefreshToken{}, false, err
+	}
+
+	used, _ := result[0].(int64)
+	fields, _ := result[1].([]interface{})
+	values := make(map[string]string, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		k, _ := fields[i].(string)
+		v, _ := fields[i+1].(string)
+		values[k] = v
+	}
+
+	userID, err := strconv.Atoi(values["user_id"])
+	if err != nil {
+		return domain.RefreshToken{}, false, domain.ErrInvalidToken
+	}
+
+	return domain.RefreshToken{
+		Token:            token,
+		UserID:           userID,
+		Family:           values["family"],
+		SessionExpiresAt: unixTime(values["expires_at"]),
+		Policy:           domain.SessionPolicy(values["policy"]),
+	}, used == 1, nil
+}
+
+// GetRefreshToken looks up a refresh token without using it.
+func (s *sessionRedisRepository) GetRefreshToken(token string) (domain.RefreshToken, error) {
+	if token == "" {
+		return domain.RefreshToken{}, domain.ErrInvalidToken
+	}
+
+	values, err := s.client.HGetAll(context.Background(), refreshTokenPrefix+hashRefreshToken(token)).Result()
+	if err != nil {
+		return domain.RefreshToken{}, err
+	}
+	if len(values) == 0 {
+		return domain.RefreshToken{}, domain.ErrInvalidToken
+	}
+
+	userID, err := strconv.Atoi(values["user_id"])
+	if err != nil {
+		return domain.RefreshToken{}, domain.ErrInvalidToken
+	}
+
+	return domain.RefreshToken{
+		Token:            token,
+		UserID:           userID,
+		Family:           values["family"],
+		SessionExpiresAt: unixTime(values["expires_at"]),
+		Policy:           domain.SessionPolicy(values["policy"]),
+	}, nil
+}
+
+func (s *sessionRedisRepository) RevokeRefreshFamily(family string) error {
+	if family == "" {
+		return domain.ErrInvalidToken
+	}
+
+	familyKey := refreshFamilyPrefix + family
+	values, err := s.client.HGetAll(context.Background(), familyKey).Result()
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	strID := values["user_id"]
+	_, err = s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Del(context.Background(), familyKey)
+		pipe.ZRem(context.Background(), userFamiliesPrefix+strID, family)
+		if values["refresh"] != "" {
+			pipe.Del(context.Background(), refreshTokenPrefix+values["refresh"])
+		}
+		if values["access"] != "" {
+			pipe.Del(context.Background(), sessionPrefix+values["access"], accessFamilyPrefix+values["access"])
+			pipe.SRem(context.Background(), userSessionsPrefix+strID, values["access"])
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// familyByAccess finds the family whose current access token is the given one.
+func (s *sessionRedisRepository) familyByAccess(access string) (string, error) {
+	family, err := s.client.Get(context.Background(), accessFamilyPrefix+access).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return family, nil
+}
+
+func (s *sessionRedisRepository) revokeUserFamilies(strID string, keep string) error {
+	families, err := s.client.ZRange(context.Background(), userFamiliesPrefix+strID, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, family := range families {
+		if family == keep {
+			continue
+		}
+		if err = s.RevokeRefreshFamily(family); err != nil {
+			return err
+		}
+		// the family hash may already be expired, drop it from the index anyway
+		if err = s.client.ZRem(context.Background(), userFamiliesPrefix+strID, family).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
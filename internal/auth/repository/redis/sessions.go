@@ -0,0 +1,206 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// A session as seen by the user is a refresh token family: it survives
+// access token rotation and keeps device metadata in the family hash.
+
+// TouchSession records activity of the session behind the access token and
+// moves the expiry of its family and current refresh token by the idle timeout
+// of the session policy, but not past the absolute end of the session. Empty
+// ID means the token does not belong to a session.
+func (s *sessionRedisRepository) TouchSession(token string, ip string, userAgent string) (domain.SessionInfo, error) {
+	if token == "" {
+		return domain.SessionInfo{}, domain.ErrInvalidToken
+	}
+
+	family, err := s.familyByAccess(token)
+	if err != nil {
+		return domain.SessionInfo{}, err
+	}
+	if family == "" {
+		return domain.SessionInfo{}, nil
+	}
+
+	familyKey := refreshFamilyPrefix + family
+	values, err := s.client.HGetAll(context.Background(), familyKey).Result()
+	if err != nil {
+		return domain.SessionInfo{}, err
+	}
+	if len(values) == 0 {
+		return domain.SessionInfo{}, nil
+	}
+
+	now := time.Now()
+	info := sessionInfo(family, values)
+	info.LastSeen = now.Truncate(time.Second)
+	info.IP = ip
+	info.UserAgent = userAgent
+
+	idle, _ := strconv.ParseInt(values["idle"], 10, 64)
+	deadline := now.Add(time.Duration(idle) * time.Second)
+	if end := unixTime(values["expires_at"]); !end.IsZero() && end.Before(deadline) {
+		deadline = end
+	}
+
+	userKey := userFamiliesPrefix + values["user_id"]
+	ttl, err := s.client.TTL(context.Background(), userKey).Result()
+	if err != nil {
+		return domain.SessionInfo{}, err
+	}
+
+	_, err = s.client.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.HSet(context.Background(), familyKey,
+			"last_seen", now.Unix(),
+			"ip", ip,
+			"user_agent", userAgent,
+		)
+		if idle <= 0 {
+			return nil
+		}
+		pipe.ExpireAt(context.Background(), familyKey, deadline)
+		if values["refresh"] != "" {
+			pipe.ExpireAt(context.Background(), refreshTokenPrefix+values["refresh"], deadline)
+		}
+		// the index lives as long as the longest family of the user
+		if now.Add(ttl).Before(deadline) {
+			pipe.ExpireAt(context.Background(), userKey, deadline)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.SessionInfo{}, err
+	}
+
+	if idle > 0 {
+		info.ExpiresAt = deadline
+	}
+
+	return info, nil
+}
+
+// GetSessions returns the sessions of the user, newest first. Families that
+// have already expired are dropped from the index on the way.
+func (s *sessionRedisRepository) GetSessions(id int, token string) ([]domain.SessionInfo, error) {
+	if id <= 0 {
+		return nil, domain.ErrBadRequest
+	}
+
+	current, err := s.familyByAccess(token)
+	if err != nil {
+		return nil, err
+	}
+
+	strID := strconv.Itoa(id)
+	families, err := s.client.ZRevRange(context.Background(), userFamiliesPrefix+strID, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]domain.SessionInfo, 0, len(families))
+	for _, family := range families {
+		values, err := s.client.HGetAll(context.Background(), refreshFamilyPrefix+family).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			if err = s.client.ZRem(context.Background(), userFamiliesPrefix+strID, family).Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		ttl, err := s.client.TTL(context.Background(), refreshFamilyPrefix+family).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		info := sessionInfo(family, values)
+		info.Current = family == current
+		if ttl > 0 {
+			info.ExpiresAt = time.Now().Add(ttl).Truncate(time.Second)
+		}
+		sessions = append(sessions, info)
+	}
+
+	return sessions, nil
+}
+
+// GetSessionIDs returns IDs of live sessions of the user, oldest first. Among
+// sessions created in the same millisecond the order is by ID.
+func (s *sessionRedisRepository) GetSessionIDs(id int) ([]string, error) {
+	if id <= 0 {
+		return nil, domain.ErrBadRequest
+	}
+
+	userKey := userFamiliesPrefix + strconv.Itoa(id)
+	families, err := s.client.ZRange(context.Background(), userKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(families))
+	for _, family := range families {
+		exists, err := s.client.Exists(context.Background(), refreshFamilyPrefix+family).Result()
+		if err != nil {
+			return nil, err
+		}
+		if exists == 0 {
+			if err = s.client.ZRem(context.Background(), userKey, family).Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		ids = append(ids, family)
+	}
+
+	return ids, nil
+}
+
+func (s *sessionRedisRepository) DeleteSession(id int, sessionID string) error {
+	if id <= 0 || sessionID == "" {
+		return domain.ErrBadRequest
+	}
+
+	owner, err := s.client.HGet(context.Background(), refreshFamilyPrefix+sessionID, "user_id").Result()
+	if errors.Is(err, redis.Nil) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if owner != strconv.Itoa(id) {
+		return domain.ErrNotFound
+	}
+
+	return s.RevokeRefreshFamily(sessionID)
+}
+
+func sessionInfo(family string, values map[string]string) domain.SessionInfo {
+	return domain.SessionInfo{
+		ID:        family,
+		CreatedAt: unixTime(values["created_at"]),
+		LastSeen:  unixTime(values["last_seen"]),
+		IP:        values["ip"],
+		UserAgent: values["user_agent"],
+		Policy:    domain.SessionPolicy(values["policy"]),
+	}
+}
+
+func unixTime(v string) time.Time {
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || sec == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(sec, 0)
+}
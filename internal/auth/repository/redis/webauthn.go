@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const webAuthnSessionPrefix = "webauthn:"
+
+func (s *sessionRedisRepository) AddWebAuthnSession(key string, data []byte, ttl time.Duration) error {
+	if key == "" || len(data) == 0 {
+		return domain.ErrBadRequest
+	}
+
+	err := s.client.Set(context.Background(), webAuthnSessionPrefix+key, data, ttl).Err()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *sessionRedisRepository) ConsumeWebAuthnSession(key string) ([]byte, error) {
+	if key == "" {
+		return nil, domain.ErrInvalidToken
+	}
+
+	data, err := s.client.GetDel(context.Background(), webAuthnSessionPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
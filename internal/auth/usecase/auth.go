@@ -1,65 +1,109 @@
 package usecase
 
 import (
-	"bytes"
 	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/certified-juniors/AtomHack/internal/domain"
 	logs "github.com/certified-juniors/AtomHack/internal/logger"
+	"github.com/certified-juniors/AtomHack/internal/password"
 
-	"github.com/golang-jwt/jwt"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/go-cmp/cmp"
-	"golang.org/x/crypto/argon2"
 )
 
 type authUsecase struct {
 	authRepo    domain.AuthRepository
 	sessionRepo domain.SessionRepository
 	jwtSecret   []byte
+	keyRing     *KeyRing
+	params      domain.AuthParams
+	webAuthn    *webauthn.WebAuthn
+	magicLink   []byte
+	policy      password.Policy
+	hashParams  argon2Params
+	dummyHash   []byte
+	dummyOnce   sync.Once
 }
 
-func NewAuthUsecase(ar domain.AuthRepository, sr domain.SessionRepository, js []byte) domain.AuthUsecase {
+func NewAuthUsecase(ar domain.AuthRepository, sr domain.SessionRepository, js []byte, kr *KeyRing, params domain.AuthParams) domain.AuthUsecase {
 	return &authUsecase{
 		authRepo:    ar,
 		sessionRepo: sr,
 		jwtSecret:   js,
+		keyRing:     kr,
+		params:      params,
+		webAuthn:    newWebAuthn(params),
+		magicLink:   newMagicLinkKey(params),
+		policy:      newPasswordPolicy(params),
+		hashParams:  newArgon2Params(params),
 	}
 }
 
 func (u *authUsecase) Login(credentials domain.Credentials) (domain.Session, int, error) {
 	expectedUser, err := u.authRepo.GetByEmail(credentials.Email)
-	if err != nil {
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
 		return domain.Session{}, 0, err
 	}
 	logs.Logger.Debug("Usecase Login expected user:", expectedUser)
 
-	if !checkPasswords(expectedUser.Password, credentials.Password) {
-		return domain.Session{}, 0, domain.ErrWrongCredentials
-	}
+	unknown := errors.Is(err, domain.ErrNotFound)
 
-	if !expectedUser.Confirmed {
-		return domain.Session{}, 0, domain.ErrUnconfirmedUser
+	if err = u.checkLoginBlock(credentials.Email, credentials.IP); err != nil {
+		return domain.Session{}, 0, err
+	}
+	if unknown {
+		// spend the same time as on a wrong password
+		_, _ = u.verifyPassword(u.dummyPasswordHash(), credentials.Password)
+		if u.params.AntiEnumeration {
+			return domain.Session{}, 0, u.loginFailed(credentials.Email, false, credentials.IP, domain.ErrWrongCredentials)
+		}
+		return domain.Session{}, 0, u.loginFailed(credentials.Email, false, credentials.IP, domain.ErrNotFound)
 	}
 
-	t, err := u.GenerateJWT(credentials.Email)
-	if err != nil {
+	ok, outdated := u.verifyPassword(expectedUser.Password, credentials.Password)
+	if !ok {
+		return domain.Session{}, 0, u.loginFailed(credentials.Email, true, credentials.IP, domain.ErrWrongCredentials)
+	}
+	if outdated {
+		u.rehashPassword(expectedUser.ID, credentials.Password)
+	}
+	if err = u.sessionRepo.ResetLoginFailures(loginAccount(credentials.Email)); err != nil {
 		return domain.Session{}, 0, err
 	}
 
-	logs.Logger.Debug("usecase/http Login jwt:\n", t)
+	return u.completeLogin(expectedUser, sessionPolicy(credentials.RememberMe))
+}
 
-	session := domain.Session{
-		Token:     t,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-		UserID:    expectedUser.ID,
+// completeLogin is the common tail of every primary login method: it issues
+// a session, or an MFA challenge when the user has a second factor enabled.
+func (u *authUsecase) completeLogin(user domain.User, policy domain.SessionPolicy) (domain.Session, int, error) {
+	if !user.Confirmed {
+		return domain.Session{}, 0, domain.ErrUnconfirmedUser
 	}
-	if err = u.sessionRepo.Add(session); err != nil {
+
+	totp, err := u.authRepo.GetTOTP(user.ID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return domain.Session{}, 0, err
+	}
+	if totp.Enabled {
+		challenge, err := u.mfaChallenge(user.ID, policy)
+		if err != nil {
+			return domain.Session{}, 0, err
+		}
+		return challenge, user.ID, domain.ErrMFARequired
+	}
+
+	session, err := u.createSession(user, policy)
+	if err != nil {
 		return domain.Session{}, 0, err
 	}
 
-	return session, expectedUser.ID, nil
+	return session, user.ID, nil
 }
 
 func (u *authUsecase) Logout(token string) error {
@@ -79,13 +123,19 @@ func (u *authUsecase) Register(user domain.User) (int, error) {
 		return 0, domain.ErrBadRequest
 	}
 
+	if err := u.checkPassword(user.Password, user); err != nil {
+		return 0, err
+	}
+
 	if exists, err := u.authRepo.UserExists(user.Email); exists && err == nil {
+		if u.params.AntiEnumeration {
+			// spend the same time as on a new user
+			_ = u.hashPassword(user.Password)
+		}
 		return 0, domain.ErrAlreadyExists
 	}
 
-	salt := make([]byte, 8)
-	rand.Read(salt)
-	user.Password = HashPassword(salt, user.Password)
+	user.Password = u.hashPassword(user.Password)
 	if id, err := u.authRepo.AddUser(user); err != nil {
 		return 0, err
 	} else {
@@ -94,37 +144,55 @@ func (u *authUsecase) Register(user domain.User) (int, error) {
 }
 
 func (u *authUsecase) ConfirmUser(pair domain.ConfirmPair) (domain.Session, error) {
-	if pair.ID == 0 || pair.Code == "" {
+	if (pair.ID == 0 && pair.Email == "") || pair.Code == "" {
 		return domain.Session{}, domain.ErrBadRequest
 	}
+	if pair.ID == 0 {
+		// an unknown email looks like a wrong code
+		user, err := u.authRepo.GetByEmail(pair.Email)
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.Session{}, domain.ErrInvalidCode
+		}
+		if err != nil {
+			return domain.Session{}, err
+		}
+		pair.ID = user.ID
+	}
+
+	// a missing code is ErrInvalidCode as well, the same answer as above
 	expectedCode, err := u.sessionRepo.GetCodeByID(strconv.Itoa(pair.ID))
 	if err != nil {
 		return domain.Session{}, err
 	}
 
 	if expectedCode != pair.Code {
-		return domain.Session{}, domain.ErrBadRequest
+		attempts, err := u.sessionRepo.IncrCodeAttempts(pair.ID, u.params.ConfirmCodeTTL)
+		if err != nil {
+			return domain.Session{}, err
+		}
+		if attempts >= u.params.ConfirmMaxAttempts {
+			if err = u.sessionRepo.DeleteCodeByID(pair.ID); err != nil {
+				return domain.Session{}, err
+			}
+		}
+		return domain.Session{}, domain.ErrInvalidCode
 	}
 
-	email, err := u.authRepo.ConfirmUser(pair.ID)
-	if err != nil {
+	if _, err = u.authRepo.ConfirmUser(pair.ID); err != nil {
 		return domain.Session{}, err
 	}
 
-	t, err := u.GenerateJWT(email)
-	if err != nil {
+	if err = u.sessionRepo.DeleteCodeByID(pair.ID); err != nil {
 		return domain.Session{}, err
 	}
 
-	logs.Logger.Debug("usecase/http ConfirmUser jwt:\n", t)
-
-	session := domain.Session{
-		Token:     t,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-		UserID:    pair.ID,
+	user, err := u.authRepo.GetByID(pair.ID)
+	if err != nil {
+		return domain.Session{}, err
 	}
 
-	if err = u.sessionRepo.Add(session); err != nil {
+	session, err := u.createSession(user, domain.BrowserSessionPolicy)
+	if err != nil {
 		return domain.Session{}, err
 	}
 
@@ -136,7 +204,11 @@ func (u *authUsecase) AddCodeByID(id int, code string) error {
 		return domain.ErrBadRequest
 	}
 
-	err := u.sessionRepo.AddCodeByID(id, code)
+	if _, err := u.sessionRepo.SetCodeCooldown(id, u.params.ConfirmResendCooldown); err != nil {
+		return err
+	}
+
+	err := u.sessionRepo.AddCodeByID(id, code, u.params.ConfirmCodeTTL)
 	if err != nil {
 		return err
 	}
@@ -144,6 +216,54 @@ func (u *authUsecase) AddCodeByID(id int, code string) error {
 	return nil
 }
 
+// ResendCode returns the email to send the new code to. When the user is
+// looked up by email in anti-enumeration mode, an unknown or confirmed user
+// and the cooldown are not reported: the email is empty and nothing is sent.
+func (u *authUsecase) ResendCode(resend domain.ConfirmResend, code string) (string, error) {
+	if (resend.ID <= 0 && resend.Email == "") || code == "" {
+		return "", domain.ErrBadRequest
+	}
+	silent := resend.ID <= 0 && u.params.AntiEnumeration
+
+	var user domain.User
+	var err error
+	if resend.ID > 0 {
+		user, err = u.authRepo.GetByID(resend.ID)
+	} else {
+		user, err = u.authRepo.GetByEmail(resend.Email)
+	}
+	if errors.Is(err, domain.ErrNotFound) && silent {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if user.Confirmed {
+		if silent {
+			return "", nil
+		}
+		return "", domain.ErrBadRequest
+	}
+
+	ok, err := u.sessionRepo.SetCodeCooldown(user.ID, u.params.ConfirmResendCooldown)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		if silent {
+			return "", nil
+		}
+		return "", domain.ErrTooManyRequests
+	}
+
+	if err = u.sessionRepo.AddCodeByID(user.ID, code, u.params.ConfirmCodeTTL); err != nil {
+		return "", err
+	}
+
+	return user.Email, nil
+}
+
 func (u *authUsecase) GetByID(id int) (domain.User, error) {
 	if id == 0 {
 		return domain.User{}, domain.ErrBadRequest
@@ -172,40 +292,252 @@ func (u *authUsecase) GetUserID(token string) (string, error) {
 	return id, nil
 }
 
-func (u *authUsecase) GenerateJWT(email string) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+func (u *authUsecase) ForgotPassword(email string) (string, error) {
+	if email == "" {
+		return "", domain.ErrBadRequest
+	}
+
+	user, err := u.authRepo.GetByEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err = u.sessionRepo.AddResetToken(token, user.ID, u.params.ResetTokenTTL); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (u *authUsecase) ResetPassword(reset domain.PasswordReset) error {
+	if reset.Token == "" || len(reset.Password) == 0 {
+		return domain.ErrBadRequest
+	}
+
+	id, err := u.sessionRepo.GetResetToken(reset.Token)
+	if err != nil {
+		return err
+	}
+
+	user, err := u.authRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	// the token is spent only on an acceptable password
+	if err = u.checkPassword(reset.Password, user); err != nil {
+		return err
+	}
+
+	if _, err = u.sessionRepo.ConsumeResetToken(reset.Token); err != nil {
+		return err
+	}
+
+	if err = u.authRepo.UpdatePassword(id, u.hashPassword(reset.Password)); err != nil {
+		return err
+	}
+
+	if err = u.sessionRepo.DeleteByUserID(id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *authUsecase) ChangePassword(id int, token string, change domain.PasswordChange) error {
+	if id == 0 || len(change.OldPassword) == 0 || len(change.NewPassword) == 0 {
+		return domain.ErrBadRequest
+	}
+
+	user, err := u.authRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	// wrong current passwords count towards the login lockout, a stolen
+	// session must not become an unlimited password oracle
+	if err = u.checkLoginBlock(user.Email, ""); err != nil {
+		return err
+	}
+
+	passHash, err := u.authRepo.GetPasswordByID(id)
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := u.verifyPassword(passHash, change.OldPassword); !ok {
+		return u.loginFailed(user.Email, true, "", domain.ErrWrongCredentials)
+	}
+	if err = u.sessionRepo.ResetLoginFailures(loginAccount(user.Email)); err != nil {
+		return err
+	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	claims["email"] = email
+	if err = u.checkPassword(change.NewPassword, user); err != nil {
+		return err
+	}
+
+	if err = u.authRepo.UpdatePassword(id, u.hashPassword(change.NewPassword)); err != nil {
+		return err
+	}
+
+	if change.RevokeOtherSessions {
+		if err = u.sessionRepo.DeleteOtherSessions(id, token); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *authUsecase) Refresh(refreshToken string) (domain.Session, int, error) {
+	if refreshToken == "" {
+		return domain.Session{}, 0, domain.ErrInvalidToken
+	}
+
+	rt, fresh, err := u.sessionRepo.UseRefreshToken(refreshToken)
+	if err != nil {
+		return domain.Session{}, 0, err
+	}
+	if !fresh {
+		logs.Logger.Warn("refresh token reuse detected, revoking family of user ", rt.UserID)
+		if err = u.sessionRepo.RevokeRefreshFamily(rt.Family); err != nil {
+			return domain.Session{}, 0, err
+		}
+		return domain.Session{}, 0, domain.ErrInvalidToken
+	}
+
+	if rt.SessionExpiresAt.IsZero() {
+		// family created before sessions had an absolute lifetime
+		_, maxLifetime := u.sessionLifetime(rt.Policy)
+		rt.SessionExpiresAt = time.Now().Add(maxLifetime)
+	}
+	if !time.Now().Before(rt.SessionExpiresAt) {
+		if err = u.sessionRepo.RevokeRefreshFamily(rt.Family); err != nil {
+			return domain.Session{}, 0, err
+		}
+		return domain.Session{}, 0, domain.ErrInvalidToken
+	}
+
+	user, err := u.authRepo.GetByID(rt.UserID)
+	if err != nil {
+		return domain.Session{}, 0, err
+	}
 
-	tokenString, err := token.SignedString(u.jwtSecret)
+	session, err := u.issueSession(user, rt.Family, rt.Policy, rt.SessionExpiresAt)
 	if err != nil {
+		return domain.Session{}, 0, err
+	}
+
+	return session, user.ID, nil
+}
+
+func (u *authUsecase) createSession(user domain.User, policy domain.SessionPolicy) (domain.Session, error) {
+	if err := u.enforceSessionLimit(user); err != nil {
+		return domain.Session{}, err
+	}
+
+	family, err := generateToken()
+	if err != nil {
+		return domain.Session{}, err
+	}
+
+	_, maxLifetime := u.sessionLifetime(policy)
+	return u.issueSession(user, family, policy, time.Now().Add(maxLifetime))
+}
+
+// issueSession creates an access token and the next refresh token of the
+// family. The refresh token lives for the idle timeout of the policy, neither
+// token outlives the absolute end of the session.
+func (u *authUsecase) issueSession(user domain.User, family string, policy domain.SessionPolicy, sessionExpiresAt time.Time) (domain.Session, error) {
+	idle, _ := u.sessionLifetime(policy)
+	now := time.Now()
+	expiresAt := earliest(now.Add(u.params.AccessTokenTTL), sessionExpiresAt)
+
+	t, err := u.GenerateJWT(user, expiresAt)
+	if err != nil {
+		return domain.Session{}, err
+	}
+
+	logs.Logger.Debug("usecase issueSession jwt:\n", t)
+
+	refresh, err := generateToken()
+	if err != nil {
+		return domain.Session{}, err
+	}
+
+	session := domain.Session{
+		Token:            t,
+		ExpiresAt:        expiresAt,
+		UserID:           user.ID,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: earliest(now.Add(idle), sessionExpiresAt),
+		Policy:           policy,
+	}
+	if err = u.sessionRepo.Add(session); err != nil {
+		return domain.Session{}, err
+	}
+
+	err = u.sessionRepo.AddRefreshToken(domain.RefreshToken{
+		Token:            refresh,
+		UserID:           user.ID,
+		Family:           family,
+		AccessToken:      t,
+		ExpiresAt:        session.RefreshExpiresAt,
+		SessionExpiresAt: sessionExpiresAt,
+		Policy:           policy,
+		IdleTimeout:      idle,
+	})
+	if err != nil {
+		return domain.Session{}, err
+	}
+
+	return session, nil
+}
+
+func earliest(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+
+	return a
+}
+
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
 
-	return tokenString, nil
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
-//func (u *authUsecase) ParseJWT(tokenString string) (string, error) {
-//	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-//		return u.jwtSecret, nil
-//	})
-//
-//	if err != nil {
-//		return "", err
-//	}
-//
-//	return token.Raw, nil
-//}
+func newPasswordPolicy(params domain.AuthParams) password.Policy {
+	policy := password.Policy{
+		MinLength:  params.PasswordMinLength,
+		MaxLength:  params.PasswordMaxLength,
+		MinClasses: params.PasswordMinClasses,
+		MinScore:   params.PasswordMinScore,
+	}
+	if params.PasswordBreachFilter == "" {
+		return policy
+	}
+
+	filter, err := password.LoadBloomFilter(params.PasswordBreachFilter)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/usecase", "newPasswordPolicy", err, "Breached password check is disabled")
+		return policy
+	}
+	policy.Breached = filter
 
-func HashPassword(salt []byte, password []byte) []byte {
-	hashedPass := argon2.IDKey(password, salt, 1, 64*1024, 4, 32)
-	return append(salt, hashedPass...)
+	return policy
 }
 
-func checkPasswords(passHash []byte, plainPassword []byte) bool {
-	salt := make([]byte, 8)
-	_ = copy(salt, passHash)
-	userPassHash := HashPassword(salt, plainPassword)
-	return bytes.Equal(userPassHash, passHash)
+// checkPassword applies the password policy to a new password of the user.
+func (u *authUsecase) checkPassword(plainPassword []byte, user domain.User) error {
+	return u.policy.Check(plainPassword, user.Email, user.Name, user.Surname, user.MiddleName)
 }
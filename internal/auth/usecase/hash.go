@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Passwords are stored as argon2id PHC strings:
+//
+//	$argon2id$v=19$m=65536,t=3,p=4,keyid=<pepper id>$<salt>$<hash>
+//
+// with unpadded base64 salt and hash. With a pepper the password is first
+// replaced by HMAC-SHA256 keyed with the pepper, keyid names the pepper and
+// is absent for hashes made without one. Hashes created before that are raw
+// salt(8) || argon2id(t=1, m=64MiB, p=4) with a 32 byte key.
+//
+// Stored costs are bounded like those of imported hashes, a hash asking for
+// more is rejected rather than let one login allocate gigabytes.
+const (
+	argon2Prefix  = "$argon2id$"
+	saltLength    = 16
+	keyLength     = 32
+	legacySaltLen = 8
+
+	maxArgon2Memory  = 1 << 20 // KiB, 1 GiB
+	maxArgon2Time    = 16
+	maxArgon2Threads = 64
+	maxArgon2KeyLen  = 64
+)
+
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+	keyLen  uint32
+	// pepperID is the pepper applied before hashing, empty for none
+	pepperID string
+}
+
+// argon2Limits are the highest costs accepted from a stored hash.
+var argon2Limits = map[string]uint64{"m": maxArgon2Memory, "t": maxArgon2Time, "p": maxArgon2Threads}
+
+var legacyArgon2Params = argon2Params{time: 1, memory: 64 * 1024, threads: 4, keyLen: keyLength}
+
+func newArgon2Params(params domain.AuthParams) argon2Params {
+	p := argon2Params{
+		time:     uint32(min(params.PasswordHashTime, maxArgon2Time)),
+		memory:   uint32(min(params.PasswordHashMemory, maxArgon2Memory)),
+		threads:  uint8(min(params.PasswordHashThreads, maxArgon2Threads)),
+		keyLen:   keyLength,
+		pepperID: params.PasswordPepperID,
+	}
+	if _, ok := params.PasswordPeppers[p.pepperID]; p.pepperID != "" && !ok {
+		logs.LogError(logs.Logger, "auth/usecase", "newArgon2Params", domain.ErrNotFound, "Pepper "+p.pepperID+" is not loaded, passwords are hashed without pepper")
+		p.pepperID = ""
+	}
+
+	return p
+}
+
+func (u *authUsecase) hashPassword(password []byte) []byte {
+	salt := make([]byte, saltLength)
+	_, _ = rand.Read(salt)
+
+	p := u.hashParams
+	key := argon2.IDKey(u.pepper(password, p.pepperID), salt, p.time, p.memory, p.threads, p.keyLen)
+
+	keyID := ""
+	if p.pepperID != "" {
+		keyID = ",keyid=" + p.pepperID
+	}
+
+	return []byte(fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d%s$%s$%s",
+		argon2Prefix, argon2.Version, p.memory, p.time, p.threads, keyID,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	))
+}
+
+// pepper keys the password with a secret kept outside the database, so that
+// a stolen hash can not be cracked without it.
+func (u *authUsecase) pepper(password []byte, pepperID string) []byte {
+	if pepperID == "" {
+		return password
+	}
+
+	mac := hmac.New(sha256.New, u.params.PasswordPeppers[pepperID])
+	mac.Write(password)
+	return mac.Sum(nil)
+}
+
+// verifyPassword checks the password against a stored hash in constant time,
+// dispatching on the hash format. outdated reports that the hash should be
+// replaced by an argon2id one with the current parameters, which is always
+// the case for legacy and imported hashes.
+func (u *authUsecase) verifyPassword(encoded []byte, password []byte) (ok bool, outdated bool) {
+	switch format := string(encoded); {
+	case strings.HasPrefix(format, argon2Prefix):
+		return u.verifyArgon2(encoded, password)
+	case isBcrypt(format):
+		return verifyBcrypt(encoded, password), true
+	case strings.HasPrefix(format, djangoPBKDF2Prefix), strings.HasPrefix(format, pbkdf2Prefix):
+		return verifyPBKDF2(format, password), true
+	case strings.HasPrefix(format, scryptPrefix):
+		return verifyScrypt(format, password), true
+	default:
+		return verifyLegacy(encoded, password), true
+	}
+}
+
+func (u *authUsecase) verifyArgon2(encoded []byte, password []byte) (bool, bool) {
+	p, salt, key, err := decodeArgon2(string(encoded))
+	if err != nil {
+		return false, false
+	}
+	if _, ok := u.params.PasswordPeppers[p.pepperID]; p.pepperID != "" && !ok {
+		logs.LogError(logs.Logger, "auth/usecase", "verifyPassword", domain.ErrNotFound, "Pepper "+p.pepperID+" of password hash is not loaded")
+		return false, false
+	}
+
+	computed := argon2.IDKey(u.pepper(password, p.pepperID), salt, p.time, p.memory, p.threads, p.keyLen)
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return false, false
+	}
+
+	return true, p != u.hashParams || len(salt) != saltLength
+}
+
+func verifyLegacy(encoded []byte, password []byte) bool {
+	if len(encoded) != legacySaltLen+keyLength {
+		return false
+	}
+
+	p := legacyArgon2Params
+	salt := encoded[:legacySaltLen]
+	computed := argon2.IDKey(password, salt, p.time, p.memory, p.threads, p.keyLen)
+
+	return subtle.ConstantTimeCompare(computed, encoded[legacySaltLen:]) == 1
+}
+
+func decodeArgon2(encoded string) (argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return argon2Params{}, nil, nil, domain.ErrBadRequest
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2Params{}, nil, nil, domain.ErrBadRequest
+	}
+
+	var p argon2Params
+	for _, param := range strings.Split(parts[3], ",") {
+		name, value, _ := strings.Cut(param, "=")
+		if name == "keyid" {
+			p.pepperID = value
+			continue
+		}
+
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return argon2Params{}, nil, nil, domain.ErrBadRequest
+		}
+		if limit, ok := argon2Limits[name]; ok && n > limit {
+			return argon2Params{}, nil, nil, domain.ErrBadRequest
+		}
+		switch name {
+		case "m":
+			p.memory = uint32(n)
+		case "t":
+			p.time = uint32(n)
+		case "p":
+			p.threads = uint8(n)
+		}
+	}
+	if p.time == 0 || p.memory == 0 || p.threads == 0 {
+		return argon2Params{}, nil, nil, domain.ErrBadRequest
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2Params{}, nil, nil, domain.ErrBadRequest
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 || len(key) > maxArgon2KeyLen {
+		return argon2Params{}, nil, nil, domain.ErrBadRequest
+	}
+	p.keyLen = uint32(len(key))
+
+	return p, salt, key, nil
+}
+
+// rehashPassword upgrades the stored hash after a successful login. A failure
+// does not fail the login, the hash is upgraded on the next one.
+func (u *authUsecase) rehashPassword(userID int, password []byte) {
+	if err := u.authRepo.UpdatePassword(userID, u.hashPassword(password)); err != nil {
+		logs.LogError(logs.Logger, "auth/usecase", "rehashPassword", err, "Failed to upgrade password hash")
+	}
+}
+
+// dummyPasswordHash is checked against when there is no user, so that an
+// unknown email takes as long as a wrong password.
+func (u *authUsecase) dummyPasswordHash() []byte {
+	u.dummyOnce.Do(func() {
+		u.dummyHash = u.hashPassword([]byte("dummy password"))
+	})
+
+	return u.dummyHash
+}
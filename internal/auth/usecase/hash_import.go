@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Hashes of users imported from other systems are verified in their own
+// format and replaced by argon2id on the first successful login:
+//
+//	$2a$10$<bcrypt>                                  bcrypt, also $2b$ and $2y$
+//	pbkdf2_sha256$<iterations>$<salt>$<base64 hash>   Django
+//	$pbkdf2-sha256$<iterations>$<ab64 salt>$<ab64 hash> passlib
+//	$scrypt$ln=<log2 N>,r=<r>,p=<p>$<ab64 salt>$<ab64 hash> passlib
+//
+// ab64 is unpadded base64 with "." instead of "+". Costs are bounded so that
+// a malformed hash can not stall logins.
+const (
+	djangoPBKDF2Prefix = "pbkdf2_sha256$"
+	pbkdf2Prefix       = "$pbkdf2-sha256$"
+	scryptPrefix       = "$scrypt$"
+
+	maxPBKDF2Iterations = 10_000_000
+	maxScryptLogN       = 20
+	maxScryptRP         = 64
+)
+
+func isBcrypt(format string) bool {
+	return strings.HasPrefix(format, "$2a$") || strings.HasPrefix(format, "$2b$") || strings.HasPrefix(format, "$2y$")
+}
+
+func verifyBcrypt(encoded []byte, password []byte) bool {
+	// bcrypt of Go does not know the $2y$ tag of PHP, the algorithm is the same
+	if strings.HasPrefix(string(encoded), "$2y$") {
+		encoded = append([]byte("$2b$"), encoded[4:]...)
+	}
+
+	return bcrypt.CompareHashAndPassword(encoded, password) == nil
+}
+
+func verifyPBKDF2(format string, password []byte) bool {
+	var iterations, salt, hash string
+	var key []byte
+	var err error
+	if rest, ok := strings.CutPrefix(format, djangoPBKDF2Prefix); ok {
+		parts := strings.Split(rest, "$")
+		if len(parts) != 3 {
+			return false
+		}
+		iterations, salt, hash = parts[0], parts[1], parts[2]
+		key, err = base64.StdEncoding.DecodeString(hash)
+	} else {
+		parts := strings.Split(strings.TrimPrefix(format, pbkdf2Prefix), "$")
+		if len(parts) != 3 {
+			return false
+		}
+		iterations, hash = parts[0], parts[2]
+		var rawSalt []byte
+		rawSalt, err = decodeAB64(parts[1])
+		salt = string(rawSalt)
+		if err == nil {
+			key, err = decodeAB64(hash)
+		}
+	}
+	if err != nil || len(key) == 0 {
+		return false
+	}
+
+	n, err := strconv.Atoi(iterations)
+	if err != nil || n <= 0 || n > maxPBKDF2Iterations {
+		return false
+	}
+
+	computed := pbkdf2.Key(password, []byte(salt), n, len(key), sha256.New)
+	return subtle.ConstantTimeCompare(computed, key) == 1
+}
+
+func verifyScrypt(format string, password []byte) bool {
+	parts := strings.Split(strings.TrimPrefix(format, scryptPrefix), "$")
+	if len(parts) != 3 {
+		return false
+	}
+
+	var logN, r, p int
+	for _, param := range strings.Split(parts[0], ",") {
+		name, value, _ := strings.Cut(param, "=")
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return false
+		}
+		switch name {
+		case "ln":
+			logN = v
+		case "r":
+			r = v
+		case "p":
+			p = v
+		}
+	}
+	if logN <= 0 || logN > maxScryptLogN || r <= 0 || p <= 0 || r*p > maxScryptRP {
+		return false
+	}
+
+	salt, err := decodeAB64(parts[1])
+	if err != nil {
+		return false
+	}
+	key, err := decodeAB64(parts[2])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+
+	computed, err := scrypt.Key(password, salt, 1<<logN, r, p, len(key))
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(computed, key) == 1
+}
+
+func decodeAB64(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.ReplaceAll(strings.TrimRight(s, "="), ".", "+"))
+}
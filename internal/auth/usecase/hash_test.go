@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+)
+
+const testSalt = "c29tZXNhbHRzb21lc2FsdA"
+const testKey = "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U"
+
+func TestDecodeArgon2Limits(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		ok     bool
+	}{
+		{"default", "m=65536,t=3,p=4", true},
+		{"at limits", "m=1048576,t=16,p=64", true},
+		{"memory over limit", "m=1048577,t=3,p=4", false},
+		{"huge memory", "m=4294967295,t=3,p=4", false},
+		{"time over limit", "m=65536,t=17,p=4", false},
+		{"threads over limit", "m=65536,t=3,p=65", false},
+		{"pepper id", "m=65536,t=3,p=4,keyid=p1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, _, err := decodeArgon2("$argon2id$v=19$" + tt.params + "$" + testSalt + "$" + testKey)
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, domain.ErrBadRequest) {
+				t.Fatalf("expected ErrBadRequest, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyPasswordRejectsCostlyHash(t *testing.T) {
+	u := &authUsecase{}
+
+	ok, _ := u.verifyPassword([]byte("$argon2id$v=19$m=4294967295,t=3,p=4$"+testSalt+"$"+testKey), []byte("password"))
+	if ok {
+		t.Fatal("a hash over the cost limits was accepted")
+	}
+}
+
+func TestHashParamsWithinLimits(t *testing.T) {
+	u := &authUsecase{hashParams: newArgon2Params(domain.AuthParams{
+		PasswordHashTime:    100,
+		PasswordHashMemory:  8 * 1024,
+		PasswordHashThreads: 1000,
+	})}
+
+	encoded := u.hashPassword([]byte("password"))
+	if ok, _ := u.verifyPassword(encoded, []byte("password")); !ok {
+		t.Fatalf("own hash over configured limits is not verifiable: %s", encoded)
+	}
+}
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type jwtClaims struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+var signingAlgorithms = []string{
+	jwt.SigningMethodHS256.Alg(),
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
+func (u *authUsecase) GenerateJWT(user domain.User, expiresAt time.Time) (string, error) {
+	jti, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := jwtClaims{
+		Email: user.Email,
+		Role:  user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   strconv.Itoa(user.ID),
+			Issuer:    u.params.JWTIssuer,
+			Audience:  jwt.ClaimStrings{u.params.JWTAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	key, err := u.keyRing.Active()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	if key.kid != "" {
+		token.Header["kid"] = key.kid
+	}
+
+	tokenString, err := token.SignedString(key.private)
+	if err != nil {
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+func (u *authUsecase) ParseJWT(tokenString string) (domain.TokenClaims, error) {
+	if tokenString == "" {
+		return domain.TokenClaims{}, domain.ErrInvalidToken
+	}
+
+	var claims jwtClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := u.keyRing.Lookup(kid)
+		if !ok {
+			return nil, errors.New("unknown kid")
+		}
+		if token.Method.Alg() != key.Algorithm() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.public, nil
+	},
+		jwt.WithValidMethods(signingAlgorithms),
+		jwt.WithIssuer(u.params.JWTIssuer),
+		jwt.WithAudience(u.params.JWTAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/usecase", "ParseJWT", err, err.Error())
+		return domain.TokenClaims{}, domain.ErrInvalidToken
+	}
+
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return domain.TokenClaims{}, domain.ErrInvalidToken
+	}
+
+	return domain.TokenClaims{
+		ID:        claims.ID,
+		UserID:    id,
+		Email:     claims.Email,
+		Role:      claims.Role,
+		IssuedAt:  claims.IssuedAt.Time,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+func (u *authUsecase) JWKS() domain.JWKS {
+	jwks := domain.JWKS{Keys: []domain.JWK{}}
+	for _, key := range u.keyRing.Published() {
+		if !key.Symmetric() {
+			jwks.Keys = append(jwks.Keys, key.JWK())
+		}
+	}
+
+	return jwks
+}
@@ -0,0 +1,324 @@
+package usecase
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyManifestName = "keyring.json"
+
+// KeyManifest describes the keys of a key ring directory. Files are relative
+// to the directory.
+type KeyManifest struct {
+	Keys []KeyManifestEntry `json:"keys"`
+}
+
+type KeyManifestEntry struct {
+	Kid         string     `json:"kid"`
+	Alg         string     `json:"alg"`
+	File        string     `json:"file"`
+	ActivatesAt time.Time  `json:"activatesAt"`
+	RetiresAt   *time.Time `json:"retiresAt,omitempty"`
+}
+
+type ringKey struct {
+	*SigningKey
+	activatesAt time.Time
+	retiresAt   time.Time
+}
+
+func (k ringKey) retired(now time.Time) bool {
+	return !k.retiresAt.IsZero() && !now.Before(k.retiresAt)
+}
+
+// KeyRing holds the key that signs new tokens and the previously active keys
+// that are still accepted for verification until their retirement date.
+type KeyRing struct {
+	mu      sync.RWMutex
+	dir     string
+	modTime time.Time
+	keys    []ringKey
+}
+
+// NewStaticKeyRing wraps a single key that is never rotated.
+func NewStaticKeyRing(key *SigningKey) *KeyRing {
+	return &KeyRing{keys: []ringKey{{SigningKey: key}}}
+}
+
+// LoadKeyRing reads the key ring manifest and private keys from dir.
+func LoadKeyRing(dir string) (*KeyRing, error) {
+	ring := &KeyRing{dir: dir}
+	if err := ring.Reload(); err != nil {
+		return nil, err
+	}
+
+	return ring, nil
+}
+
+// Reload re-reads the manifest if it has changed since the last load.
+func (r *KeyRing) Reload() error {
+	if r.dir == "" {
+		return nil
+	}
+
+	info, err := os.Stat(filepath.Join(r.dir, keyManifestName))
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	unchanged := info.ModTime().Equal(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	manifest, err := ReadKeyManifest(r.dir)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]ringKey, 0, len(manifest.Keys))
+	for _, entry := range manifest.Keys {
+		data, err := os.ReadFile(filepath.Join(r.dir, entry.File))
+		if err != nil {
+			return err
+		}
+
+		key, err := ParseSigningKey(entry.Alg, data, entry.Kid)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", entry.Kid, err)
+		}
+
+		rk := ringKey{SigningKey: key, activatesAt: entry.ActivatesAt}
+		if entry.RetiresAt != nil {
+			rk.retiresAt = *entry.RetiresAt
+		}
+		keys = append(keys, rk)
+	}
+
+	if len(keys) == 0 {
+		return errors.New("key ring is empty")
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.modTime = info.ModTime()
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Watch periodically reloads the manifest so that rotations made by other
+// processes are picked up without a restart.
+func (r *KeyRing) Watch(interval time.Duration) {
+	if r.dir == "" {
+		return
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			if err := r.Reload(); err != nil {
+				logs.LogError(logs.Logger, "auth/usecase", "KeyRing.Watch", err, "Failed to reload key ring")
+			}
+		}
+	}()
+}
+
+// Active returns the most recently activated key that is not retired.
+func (r *KeyRing) Active() (*SigningKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	var active *ringKey
+	for i, k := range r.keys {
+		if k.retired(now) || k.activatesAt.After(now) {
+			continue
+		}
+		if active == nil || !k.activatesAt.Before(active.activatesAt) {
+			active = &r.keys[i]
+		}
+	}
+
+	if active == nil {
+		return nil, errors.New("no active signing key")
+	}
+
+	return active.SigningKey, nil
+}
+
+// Lookup finds a non-retired key by kid.
+func (r *KeyRing) Lookup(kid string) (*SigningKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	for _, k := range r.keys {
+		if k.kid == kid && !k.retired(now) {
+			return k.SigningKey, true
+		}
+	}
+
+	return nil, false
+}
+
+// Published returns every non-retired key, including keys that are not
+// active yet so verifiers can fetch them before the first token is signed.
+func (r *KeyRing) Published() []*SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]*SigningKey, 0, len(r.keys))
+	for _, k := range r.keys {
+		if !k.retired(now) {
+			keys = append(keys, k.SigningKey)
+		}
+	}
+
+	return keys
+}
+
+// RotateKeyRing generates a new key in dir that becomes active after
+// publishDelay, and schedules retirement of the current keys overlap after
+// that. Keys retired before now are removed from the ring.
+func RotateKeyRing(dir, alg string, publishDelay, overlap time.Duration) (KeyManifestEntry, error) {
+	manifest, err := ReadKeyManifest(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return KeyManifestEntry{}, err
+	}
+
+	pemData, err := GenerateSigningKeyPEM(alg)
+	if err != nil {
+		return KeyManifestEntry{}, err
+	}
+
+	key, err := ParseSigningKey(alg, pemData, "")
+	if err != nil {
+		return KeyManifestEntry{}, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	entry := KeyManifestEntry{
+		Kid:         key.ID(),
+		Alg:         alg,
+		File:        key.ID() + ".pem",
+		ActivatesAt: now.Add(publishDelay),
+	}
+
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return KeyManifestEntry{}, err
+	}
+	if err = os.WriteFile(filepath.Join(dir, entry.File), pemData, 0o600); err != nil {
+		return KeyManifestEntry{}, err
+	}
+
+	keys := make([]KeyManifestEntry, 0, len(manifest.Keys)+1)
+	for _, old := range manifest.Keys {
+		if old.RetiresAt != nil && !now.Before(*old.RetiresAt) {
+			if err = os.Remove(filepath.Join(dir, old.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return KeyManifestEntry{}, err
+			}
+			continue
+		}
+		keys = append(keys, old)
+	}
+
+	// The first key of a ring has nothing to overlap with.
+	if len(keys) == 0 {
+		entry.ActivatesAt = now
+	}
+
+	retiresAt := entry.ActivatesAt.Add(overlap)
+	for i := range keys {
+		if keys[i].RetiresAt == nil || keys[i].RetiresAt.After(retiresAt) {
+			keys[i].RetiresAt = &retiresAt
+		}
+	}
+	manifest.Keys = append(keys, entry)
+
+	if err = writeKeyManifest(dir, manifest); err != nil {
+		return KeyManifestEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// ReadKeyManifest returns the manifest of the key ring in dir.
+func ReadKeyManifest(dir string) (KeyManifest, error) {
+	var manifest KeyManifest
+
+	data, err := os.ReadFile(filepath.Join(dir, keyManifestName))
+	if err != nil {
+		return manifest, err
+	}
+
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return manifest, err
+	}
+
+	return manifest, nil
+}
+
+// writeKeyManifest replaces the manifest atomically so that running services
+// never read a partially written file.
+func writeKeyManifest(dir string, manifest KeyManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(dir, keyManifestName+".tmp")
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(dir, keyManifestName))
+}
+
+// GenerateSigningKeyPEM creates a new PKCS#8 private key for alg.
+func GenerateSigningKeyPEM(alg string) ([]byte, error) {
+	var (
+		key crypto.PrivateKey
+		err error
+	)
+
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodES256.Alg():
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("can not generate key for %s", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a JWT key: an asymmetric key pair or the legacy HS256 secret.
+type SigningKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// LoadSigningKey reads the key configured for the given algorithm. For
+// HS256 the shared secret is used, other algorithms need a PEM private key.
+// Empty kid is replaced with the RFC 7638 thumbprint of the public key.
+func LoadSigningKey(alg, path, kid string, secret []byte) (*SigningKey, error) {
+	if alg == jwt.SigningMethodHS256.Alg() {
+		if len(secret) == 0 {
+			return nil, errors.New("JWT_SECRET is required for HS256")
+		}
+		return &SigningKey{kid: kid, method: jwt.SigningMethodHS256, private: secret, public: secret}, nil
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("private key file is required for %s", alg)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSigningKey(alg, data, kid)
+}
+
+// ParseSigningKey builds a signing key for alg from a PEM encoded private key.
+func ParseSigningKey(alg string, pemData []byte, kid string) (*SigningKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM block found in private key")
+	}
+
+	private, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{kid: kid, private: private}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if alg != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("RSA key can not be used for %s", alg)
+		}
+		if k.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key must be at least 2048 bits")
+		}
+		key.method, key.public = jwt.SigningMethodRS256, &k.PublicKey
+	case *ecdsa.PrivateKey:
+		if alg != jwt.SigningMethodES256.Alg() || k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("EC key can not be used for %s", alg)
+		}
+		key.method, key.public = jwt.SigningMethodES256, &k.PublicKey
+	case ed25519.PrivateKey:
+		if alg != jwt.SigningMethodEdDSA.Alg() {
+			return nil, fmt.Errorf("Ed25519 key can not be used for %s", alg)
+		}
+		key.method, key.public = jwt.SigningMethodEdDSA, k.Public()
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+
+	if key.kid == "" {
+		if key.kid, err = thumbprint(key.JWK()); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+func parsePrivateKey(der []byte) (crypto.PrivateKey, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+
+	return nil, errors.New("failed to parse private key, expected PKCS#8, PKCS#1 or SEC 1")
+}
+
+func (k *SigningKey) ID() string {
+	return k.kid
+}
+
+func (k *SigningKey) Algorithm() string {
+	return k.method.Alg()
+}
+
+func (k *SigningKey) Symmetric() bool {
+	return k.method == jwt.SigningMethodHS256
+}
+
+// JWK returns public part of the key, it is empty for symmetric keys.
+func (k *SigningKey) JWK() domain.JWK {
+	jwk := domain.JWK{Kid: k.kid, Use: "sig", Alg: k.method.Alg()}
+
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.Kty = "EC"
+		jwk.Crv = "P-256"
+		jwk.X = b64(pub.X.FillBytes(make([]byte, 32)))
+		jwk.Y = b64(pub.Y.FillBytes(make([]byte, 32)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	default:
+		return domain.JWK{}
+	}
+
+	return jwk
+}
+
+// thumbprint implements RFC 7638: SHA-256 of the required members in
+// lexicographic order.
+func thumbprint(jwk domain.JWK) (string, error) {
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", errors.New("can not compute thumbprint of symmetric key")
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return b64(sum[:]), nil
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
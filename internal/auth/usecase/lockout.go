@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"strings"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+)
+
+// checkLoginBlock rejects the attempt while the email or the IP is paused
+// after previous failures.
+func (u *authUsecase) checkLoginBlock(email string, ip string) error {
+	block, err := u.sessionRepo.GetLoginBlock(loginAccount(email), ip)
+	if err != nil {
+		return err
+	}
+	if block.RetryAfter <= 0 {
+		return nil
+	}
+
+	return blockedError(block, false)
+}
+
+// loginFailed counts the failed attempt and pauses further ones: every
+// failure doubles the delay, reaching the threshold locks the account or the
+// IP out. Unknown emails are counted too, so the answers do not tell them
+// apart from registered ones, only registered owners are notified of a
+// lockout. It returns the error to answer this attempt with.
+func (u *authUsecase) loginFailed(email string, registered bool, ip string, failure error) error {
+	email = loginAccount(email)
+	accountFailures, ipFailures, err := u.sessionRepo.RegisterLoginFailure(email, ip, u.params.LoginFailureWindow)
+	if err != nil {
+		return err
+	}
+
+	account := u.loginBlock(accountFailures, u.params.LoginLockoutThreshold)
+	byIP := u.loginBlock(ipFailures, u.params.LoginIPThreshold)
+	if err = u.sessionRepo.BlockLogin(email, ip, account, byIP); err != nil {
+		return err
+	}
+
+	if email != "" && accountFailures == u.params.LoginLockoutThreshold {
+		logs.Logger.Warn("account locked after failed logins, email ", email)
+		return blockedError(account, registered)
+	}
+	if ipFailures == u.params.LoginIPThreshold {
+		logs.Logger.Warn("ip locked after failed logins ", ip)
+		return blockedError(byIP, false)
+	}
+
+	return failure
+}
+
+// loginAccount is the key failures of an email are counted under.
+func loginAccount(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (u *authUsecase) loginBlock(failures int, threshold int) domain.LoginBlock {
+	if failures <= 0 {
+		return domain.LoginBlock{}
+	}
+	if failures >= threshold {
+		return domain.LoginBlock{Locked: true, RetryAfter: u.params.LoginLockoutDuration}
+	}
+
+	delay := u.params.LoginBackoffBase
+	for i := 1; i < failures && delay < u.params.LoginBackoffMax; i++ {
+		delay *= 2
+	}
+
+	return domain.LoginBlock{RetryAfter: earliestDuration(delay, u.params.LoginBackoffMax)}
+}
+
+func blockedError(block domain.LoginBlock, notify bool) error {
+	err := domain.ErrTooManyRequests
+	if block.Locked {
+		err = domain.ErrAccountLocked
+	}
+
+	return &domain.LoginBlockedError{Err: err, RetryAfter: block.RetryAfter, Notify: notify}
+}
+
+func earliestDuration(a, b time.Duration) time.Duration {
+	if b < a {
+		return b
+	}
+
+	return a
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+)
+
+// Magic link token is "<id>.<hmac(id)>": the id keys the Redis record, the
+// signature lets us reject forged tokens without touching Redis.
+
+// newMagicLinkKey returns the key links are signed with, nil keeps magic
+// links disabled. The key is never shared with JWT signing.
+func newMagicLinkKey(params domain.AuthParams) []byte {
+	if !params.MagicLinkEnabled {
+		return nil
+	}
+	if len(params.MagicLinkSecret) == 0 {
+		logs.LogError(logs.Logger, "auth/usecase", "newMagicLinkKey", domain.ErrFeatureDisabled, "MAGIC_LINK_SECRET is not set, magic links are disabled")
+		return nil
+	}
+
+	return params.MagicLinkSecret
+}
+
+func (u *authUsecase) RequestMagicLink(email string, nonce string) (string, error) {
+	if u.magicLink == nil {
+		return "", domain.ErrFeatureDisabled
+	}
+	if email == "" || nonce == "" {
+		return "", domain.ErrBadRequest
+	}
+
+	user, err := u.authRepo.GetByEmail(email)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err = u.sessionRepo.AddMagicLink(id, user.ID, hashNonce(nonce), u.params.MagicLinkTTL); err != nil {
+		return "", err
+	}
+
+	return id + "." + u.signMagicLink(id), nil
+}
+
+func (u *authUsecase) LoginMagicLink(token string, nonce string, rememberMe bool) (domain.Session, int, error) {
+	if u.magicLink == nil {
+		return domain.Session{}, 0, domain.ErrFeatureDisabled
+	}
+	if token == "" || nonce == "" {
+		return domain.Session{}, 0, domain.ErrInvalidToken
+	}
+
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(u.signMagicLink(id))) {
+		return domain.Session{}, 0, domain.ErrInvalidToken
+	}
+
+	userID, nonceHash, err := u.sessionRepo.ConsumeMagicLink(id)
+	if err != nil {
+		return domain.Session{}, 0, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(nonceHash), []byte(hashNonce(nonce))) != 1 {
+		return domain.Session{}, 0, domain.ErrInvalidToken
+	}
+
+	user, err := u.authRepo.GetByID(userID)
+	if err != nil {
+		return domain.Session{}, 0, err
+	}
+
+	return u.completeLogin(user, sessionPolicy(rememberMe))
+}
+
+func (u *authUsecase) signMagicLink(id string) string {
+	mac := hmac.New(sha256.New, u.magicLink)
+	mac.Write([]byte("magic-link:" + id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hashNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
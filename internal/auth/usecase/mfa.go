@@ -0,0 +1,332 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+)
+
+func (u *authUsecase) EnrollTOTP(id int) (domain.TOTPEnrollment, error) {
+	if id == 0 {
+		return domain.TOTPEnrollment{}, domain.ErrBadRequest
+	}
+
+	user, err := u.authRepo.GetByID(id)
+	if err != nil {
+		return domain.TOTPEnrollment{}, err
+	}
+
+	totp, err := u.authRepo.GetTOTP(id)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return domain.TOTPEnrollment{}, err
+	}
+	if totp.Enabled {
+		return domain.TOTPEnrollment{}, domain.ErrAlreadyExists
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return domain.TOTPEnrollment{}, err
+	}
+
+	sealed, err := encryptSecret(u.params.MFAEncryptionKey, secret)
+	if err != nil {
+		return domain.TOTPEnrollment{}, err
+	}
+
+	if err = u.authRepo.SetTOTPSecret(id, sealed); err != nil {
+		return domain.TOTPEnrollment{}, err
+	}
+
+	return domain.TOTPEnrollment{
+		Secret: totpEncoding.EncodeToString(secret),
+		URI:    totpURI(u.params.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+func (u *authUsecase) ActivateTOTP(id int, code string) ([]string, error) {
+	if id == 0 || code == "" {
+		return nil, domain.ErrBadRequest
+	}
+
+	totp, err := u.authRepo.GetTOTP(id)
+	if err != nil {
+		return nil, err
+	}
+	if totp.Enabled {
+		return nil, domain.ErrAlreadyExists
+	}
+
+	if err = u.verifyTOTP(totp, code); err != nil {
+		return nil, err
+	}
+
+	if err = u.authRepo.EnableTOTP(id); err != nil {
+		return nil, err
+	}
+
+	return u.newRecoveryCodes(id)
+}
+
+func (u *authUsecase) DisableTOTP(id int, code string) error {
+	if id == 0 || code == "" {
+		return domain.ErrBadRequest
+	}
+
+	totp, err := u.authRepo.GetTOTP(id)
+	if err != nil {
+		return err
+	}
+
+	if totp.Enabled {
+		if err = u.verifyTOTP(totp, code); err != nil {
+			return err
+		}
+	}
+
+	if err = u.authRepo.DeleteTOTP(id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *authUsecase) RegenerateRecoveryCodes(id int, code string) ([]string, error) {
+	if id == 0 || code == "" {
+		return nil, domain.ErrBadRequest
+	}
+
+	totp, err := u.authRepo.GetTOTP(id)
+	if err != nil {
+		return nil, err
+	}
+	if !totp.Enabled {
+		return nil, domain.ErrNotFound
+	}
+
+	if err = u.verifyTOTP(totp, code); err != nil {
+		return nil, err
+	}
+
+	return u.newRecoveryCodes(id)
+}
+
+func (u *authUsecase) CountRecoveryCodes(id int) (int, error) {
+	if id == 0 {
+		return 0, domain.ErrBadRequest
+	}
+
+	count, err := u.authRepo.CountRecoveryCodes(id)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (u *authUsecase) LoginMFA(login domain.MFALogin) (domain.Session, int, error) {
+	if login.ChallengeToken == "" || (login.Code == "" && login.RecoveryCode == "") {
+		return domain.Session{}, 0, domain.ErrBadRequest
+	}
+
+	challenge, err := u.sessionRepo.GetMFAChallenge(login.ChallengeToken)
+	if err != nil {
+		return domain.Session{}, 0, err
+	}
+	id := challenge.UserID
+
+	totp, err := u.authRepo.GetTOTP(id)
+	if err != nil {
+		return domain.Session{}, 0, err
+	}
+
+	if login.RecoveryCode != "" {
+		err = u.useRecoveryCode(id, login.RecoveryCode)
+	} else {
+		err = u.verifyTOTP(totp, login.Code)
+	}
+	if err != nil {
+		if !errors.Is(err, domain.ErrInvalidCode) {
+			return domain.Session{}, 0, err
+		}
+
+		attempts, err := u.sessionRepo.IncrMFAChallengeAttempts(login.ChallengeToken, u.params.MFAChallengeTTL)
+		if err != nil {
+			return domain.Session{}, 0, err
+		}
+		if attempts >= u.params.MFAMaxAttempts {
+			if err = u.sessionRepo.DeleteMFAChallenge(login.ChallengeToken); err != nil {
+				return domain.Session{}, 0, err
+			}
+		}
+		return domain.Session{}, 0, domain.ErrInvalidCode
+	}
+
+	if err = u.sessionRepo.DeleteMFAChallenge(login.ChallengeToken); err != nil {
+		return domain.Session{}, 0, err
+	}
+
+	user, err := u.authRepo.GetByID(id)
+	if err != nil {
+		return domain.Session{}, 0, err
+	}
+
+	session, err := u.createSession(user, challenge.Policy)
+	if err != nil {
+		return domain.Session{}, 0, err
+	}
+
+	return session, user.ID, nil
+}
+
+// mfaChallenge is issued instead of a session when the password is correct
+// but the user still has to pass the second factor.
+func (u *authUsecase) mfaChallenge(userID int, policy domain.SessionPolicy) (domain.Session, error) {
+	token, err := generateToken()
+	if err != nil {
+		return domain.Session{}, err
+	}
+
+	challenge := domain.MFAChallenge{UserID: userID, Policy: policy}
+	if err = u.sessionRepo.AddMFAChallenge(token, challenge, u.params.MFAChallengeTTL); err != nil {
+		return domain.Session{}, err
+	}
+
+	return domain.Session{
+		Token:     token,
+		ExpiresAt: time.Now().Add(u.params.MFAChallengeTTL),
+		UserID:    userID,
+	}, nil
+}
+
+func (u *authUsecase) verifyTOTP(totp domain.TOTP, code string) error {
+	secret, err := decryptSecret(u.params.MFAEncryptionKey, totp.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return domain.ErrInvalidCode
+	}
+
+	fresh, err := u.sessionRepo.MarkTOTPUsed(totp.UserID, step, (2*totpSkew+1)*totpPeriod*time.Second)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return domain.ErrInvalidCode
+	}
+
+	return nil
+}
+
+const (
+	recoveryCodesCount    = 10
+	recoveryCodeLength    = 10
+	recoveryCodeAlphabet  = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeSeparator = "-"
+)
+
+// newRecoveryCodes replaces all recovery codes of the user and returns the
+// new ones in plain text, only their hashes are stored.
+func (u *authUsecase) newRecoveryCodes(userID int) ([]string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	stored := make([]domain.RecoveryCode, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		plain := normalizeRecoveryCode(code)
+		codes = append(codes, code)
+		stored = append(stored, domain.RecoveryCode{
+			Lookup: u.recoveryCodeLookup(plain),
+			Hash:   u.hashPassword([]byte(plain)),
+		})
+	}
+
+	if err := u.authRepo.ReplaceRecoveryCodes(userID, stored); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// useRecoveryCode finds the code by its lookup key and verifies that one
+// hash only, so a guess costs a single argon2id run.
+func (u *authUsecase) useRecoveryCode(userID int, code string) error {
+	plain := normalizeRecoveryCode(code)
+	if len(plain) != recoveryCodeLength {
+		return domain.ErrInvalidCode
+	}
+
+	codes, err := u.authRepo.GetRecoveryCodes(userID, u.recoveryCodeLookup(plain))
+	if err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		// spend the same time as on a code that exists
+		_, _ = u.verifyPassword(u.dummyPasswordHash(), []byte(plain))
+	}
+
+	for _, c := range codes {
+		if ok, _ := u.verifyPassword(c.Hash, []byte(plain)); !ok {
+			continue
+		}
+
+		used, err := u.authRepo.UseRecoveryCode(c.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			break
+		}
+		return nil
+	}
+
+	return domain.ErrInvalidCode
+}
+
+func generateRecoveryCode() (string, error) {
+	var sb strings.Builder
+	for i := 0; i < recoveryCodeLength; i++ {
+		if i == recoveryCodeLength/2 {
+			sb.WriteString(recoveryCodeSeparator)
+		}
+
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+
+	return sb.String(), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, recoveryCodeSeparator, "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// recoveryCodeLookup is the key a recovery code is found by: an HMAC of the
+// code under a key derived from MFA_ENCRYPTION_KEY, so the table alone tells
+// nothing about the codes.
+func (u *authUsecase) recoveryCodeLookup(plain string) string {
+	derive := hmac.New(sha256.New, u.params.MFAEncryptionKey)
+	derive.Write([]byte("recovery-code-lookup"))
+
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write([]byte(plain))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"strconv"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+)
+
+// AuthenticateClient checks credentials of a service from OAUTH_CLIENTS.
+// Secrets are compared as digests so neither their content nor their length
+// leaks through timing.
+func (u *authUsecase) AuthenticateClient(id, secret string) error {
+	expected, ok := u.params.OAuthClients[id]
+
+	got := sha256.Sum256([]byte(secret))
+	want := sha256.Sum256([]byte(expected))
+	if subtle.ConstantTimeCompare(got[:], want[:]) != 1 || !ok || id == "" {
+		return domain.ErrInvalidClient
+	}
+
+	return nil
+}
+
+// Introspect describes a token as RFC 7662 does. Any token that is unknown,
+// expired or revoked is reported as inactive rather than as an error.
+func (u *authUsecase) Introspect(token string) (domain.TokenIntrospection, error) {
+	inactive := domain.TokenIntrospection{Active: false}
+	if token == "" {
+		return inactive, nil
+	}
+
+	claims, err := u.ParseJWT(token)
+	if err != nil {
+		return inactive, nil
+	}
+
+	id, err := u.sessionRepo.GetUserID(token)
+	if errors.Is(err, domain.ErrUnauthorized) {
+		return inactive, nil
+	}
+	if err != nil {
+		return inactive, err
+	}
+	if id != strconv.Itoa(claims.UserID) {
+		return inactive, nil
+	}
+
+	return domain.TokenIntrospection{
+		Active:    true,
+		Scope:     u.params.OAuthScope,
+		TokenType: "access_token",
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Sub:       id,
+		Aud:       u.params.JWTAudience,
+		Iss:       u.params.JWTIssuer,
+		Jti:       claims.ID,
+		Role:      claims.Role,
+		Email:     claims.Email,
+	}, nil
+}
+
+// RevokeToken revokes an access or refresh token as RFC 7009 does. The hint
+// only decides which kind is looked up first, unknown tokens are ignored.
+// Revoking either token ends the whole session, its refresh family included.
+// Only sessions and refresh tokens are looked up, an access token has to be
+// a JWT signed by us before its session is touched.
+func (u *authUsecase) RevokeToken(token, hint string) error {
+	if token == "" {
+		return domain.ErrBadRequest
+	}
+
+	if hint != "refresh_token" {
+		if done, err := u.revokeAccessToken(token); done || err != nil {
+			return err
+		}
+	}
+
+	rt, err := u.sessionRepo.GetRefreshToken(token)
+	if err == nil {
+		return u.sessionRepo.RevokeRefreshFamily(rt.Family)
+	}
+	if !errors.Is(err, domain.ErrInvalidToken) {
+		return err
+	}
+
+	if hint == "refresh_token" {
+		_, err = u.revokeAccessToken(token)
+		return err
+	}
+
+	return nil
+}
+
+// revokeAccessToken ends the session of an access token, done reports that
+// the token was one.
+func (u *authUsecase) revokeAccessToken(token string) (done bool, err error) {
+	if _, err = u.ParseJWT(token); err != nil {
+		return false, nil
+	}
+
+	_, err = u.sessionRepo.GetUserID(token)
+	if errors.Is(err, domain.ErrUnauthorized) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, u.sessionRepo.DeleteByToken(token)
+}
@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	"github.com/certified-juniors/AtomHack/internal/env"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+)
+
+func GetAuthParams() domain.AuthParams {
+	return domain.AuthParams{
+		ConfirmCodeTTL:        getDuration("CONFIRM_CODE_TTL", 24*time.Hour),
+		ConfirmResendCooldown: getDuration("CONFIRM_RESEND_COOLDOWN", time.Minute),
+		ConfirmMaxAttempts:    getInt("CONFIRM_MAX_ATTEMPTS", 5),
+		ResetTokenTTL:         getDuration("PASSWORD_RESET_TTL", 30*time.Minute),
+		MFAIssuer:             env.String("MFA_ISSUER", "AtomHack"),
+		MFAEncryptionKey:      getKey("MFA_ENCRYPTION_KEY"),
+		MFAChallengeTTL:       getDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+		MFAMaxAttempts:        getInt("MFA_MAX_ATTEMPTS", 5),
+		WebAuthnRPID:          os.Getenv("WEBAUTHN_RP_ID"),
+		WebAuthnRPName:        env.String("WEBAUTHN_RP_NAME", "AtomHack"),
+		WebAuthnRPOrigins:     getList("WEBAUTHN_RP_ORIGINS"),
+		WebAuthnTimeout:       getDuration("WEBAUTHN_TIMEOUT", 5*time.Minute),
+		MagicLinkEnabled:      getBool("MAGIC_LINK_ENABLED", false),
+		MagicLinkTTL:          getDuration("MAGIC_LINK_TTL", 15*time.Minute),
+		MagicLinkSecret:       getKey("MAGIC_LINK_SECRET"),
+		AccessTokenTTL:        getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		SessionIdleTimeout:    getDuration("SESSION_IDLE_TIMEOUT", 24*time.Hour),
+		SessionMaxLifetime:    getDuration("SESSION_MAX_LIFETIME", 7*24*time.Hour),
+		RememberMeIdleTimeout: getDuration("REMEMBER_ME_IDLE_TIMEOUT", 30*24*time.Hour),
+		RememberMeMaxLifetime: getDuration("REMEMBER_ME_MAX_LIFETIME", 90*24*time.Hour),
+		SessionLimit:          getInt("SESSION_LIMIT", 0),
+		SessionLimitByRole:    getLimits("SESSION_LIMIT_ROLES"),
+		SessionLimitPolicy:    domain.SessionLimitPolicy(env.String("SESSION_LIMIT_POLICY", string(domain.EvictOldestSessionLimitPolicy))),
+		LoginFailureWindow:    getDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		LoginBackoffBase:      getDuration("LOGIN_BACKOFF_BASE", time.Second),
+		LoginBackoffMax:       getDuration("LOGIN_BACKOFF_MAX", 5*time.Minute),
+		LoginLockoutThreshold: getInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LoginLockoutDuration:  getDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		LoginIPThreshold:      getInt("LOGIN_IP_THRESHOLD", 50),
+		AntiEnumeration:       getBool("ANTI_ENUMERATION", false),
+		PasswordMinLength:     getPolicyInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:     getPolicyInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordMinClasses:    getPolicyInt("PASSWORD_MIN_CLASSES", 2),
+		PasswordMinScore:      getPolicyInt("PASSWORD_MIN_SCORE", 2),
+		PasswordBreachFilter:  os.Getenv("PASSWORD_BREACH_FILTER"),
+		PasswordHashTime:      getInt("PASSWORD_HASH_TIME", 3),
+		PasswordHashMemory:    getInt("PASSWORD_HASH_MEMORY", 64*1024),
+		PasswordHashThreads:   getInt("PASSWORD_HASH_THREADS", 4),
+		PasswordPepperID:      os.Getenv("PASSWORD_PEPPER_ID"),
+		PasswordPeppers:       getPeppers("PASSWORD_PEPPERS", "PASSWORD_PEPPERS_FILE"),
+		JWTIssuer:             env.String("JWT_ISSUER", "atomhack-auth"),
+		JWTAudience:           env.String("JWT_AUDIENCE", "atomhack"),
+		JWTAlgorithm:          env.String("JWT_SIGNING_ALG", "HS256"),
+		JWTPrivateKeyFile:     os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		JWTKeyID:              os.Getenv("JWT_KEY_ID"),
+		JWTKeysDir:            os.Getenv("JWT_KEYS_DIR"),
+		JWTKeysReload:         getDuration("JWT_KEYS_RELOAD", time.Minute),
+		OAuthClients:          getClients("OAUTH_CLIENTS"),
+		OAuthScope:            env.String("OAUTH_TOKEN_SCOPE", "atomhack"),
+	}
+}
+
+func getBool(key string, def bool) bool {
+	b, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+
+	return b
+}
+
+func getList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
+// getClients parses a comma separated list of client_id:client_secret pairs.
+func getClients(key string) map[string]string {
+	clients := make(map[string]string)
+	for _, v := range getList(key) {
+		id, secret, ok := strings.Cut(v, ":")
+		if ok && id != "" && secret != "" {
+			clients[id] = secret
+		}
+	}
+
+	return clients
+}
+
+// getLimits parses a comma separated list of role:limit pairs, roles are
+// matched case-insensitively.
+func getLimits(key string) map[string]int {
+	limits := make(map[string]int)
+	for _, v := range getList(key) {
+		role, limit, ok := strings.Cut(v, ":")
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if ok && err == nil && n >= 0 {
+			limits[strings.ToLower(strings.TrimSpace(role))] = n
+		}
+	}
+
+	return limits
+}
+
+// getPeppers reads id:base64 secret pairs separated by commas from the env
+// and, one per line, from the file, so that secrets can be mounted instead
+// of set in the env. Malformed pairs are skipped.
+func getPeppers(key string, fileKey string) map[string][]byte {
+	pairs := getList(key)
+	if path := os.Getenv(fileKey); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logs.LogError(logs.Logger, "auth/usecase", "getPeppers", err, "Failed to read peppers file")
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				pairs = append(pairs, line)
+			}
+		}
+	}
+
+	peppers := make(map[string][]byte)
+	for _, v := range pairs {
+		id, secret, ok := strings.Cut(v, ":")
+		id = strings.TrimSpace(id)
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret))
+		if ok && validPepperID(id) && err == nil && len(b) >= 16 {
+			peppers[id] = b
+		}
+	}
+
+	return peppers
+}
+
+// validPepperID keeps the ID safe to put into a PHC string.
+func validPepperID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// getKey decodes a base64 AES key, nil means the key is missing or malformed.
+func getKey(key string) []byte {
+	b, err := base64.StdEncoding.DecodeString(os.Getenv(key))
+	if err != nil {
+		return nil
+	}
+
+	switch len(b) {
+	case 16, 24, 32:
+		return b
+	default:
+		return nil
+	}
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+
+	return d
+}
+
+func getInt(key string, def int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || i <= 0 {
+		return def
+	}
+
+	return i
+}
+
+// getPolicyInt reads a password policy value, unlike getInt it accepts 0,
+// which turns the rule off.
+func getPolicyInt(key string, def int) int {
+	i, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || i < 0 {
+		return def
+	}
+
+	return i
+}
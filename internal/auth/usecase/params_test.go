@@ -0,0 +1,33 @@
+package usecase
+
+import "testing"
+
+func TestGetPolicyInt(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"", 2},
+		{"0", 0},
+		{"3", 3},
+		{"-1", 2},
+		{"two", 2},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("PASSWORD_MIN_SCORE", tt.value)
+		if got := getPolicyInt("PASSWORD_MIN_SCORE", 2); got != tt.want {
+			t.Errorf("getPolicyInt(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestGetAuthParamsPolicyOff(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_SCORE", "0")
+	t.Setenv("PASSWORD_MIN_CLASSES", "0")
+
+	params := GetAuthParams()
+	if params.PasswordMinScore != 0 || params.PasswordMinClasses != 0 {
+		t.Fatalf("rules are not off: score %d, classes %d", params.PasswordMinScore, params.PasswordMinClasses)
+	}
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"strings"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+)
+
+// TouchSession records activity of the session and slides its idle window,
+// the returned session carries the new deadline.
+func (u *authUsecase) TouchSession(token, ip, userAgent string) (domain.SessionInfo, error) {
+	if token == "" {
+		return domain.SessionInfo{}, domain.ErrInvalidToken
+	}
+
+	return u.sessionRepo.TouchSession(token, ip, userAgent)
+}
+
+func (u *authUsecase) GetSessions(id int, token string) ([]domain.SessionInfo, error) {
+	if id == 0 {
+		return nil, domain.ErrBadRequest
+	}
+
+	return u.sessionRepo.GetSessions(id, token)
+}
+
+func (u *authUsecase) DeleteSession(id int, sessionID string) error {
+	if id == 0 || sessionID == "" {
+		return domain.ErrBadRequest
+	}
+
+	return u.sessionRepo.DeleteSession(id, sessionID)
+}
+
+// DeleteOtherSessions logs the user out everywhere except the current session.
+func (u *authUsecase) DeleteOtherSessions(id int, token string) error {
+	if id == 0 || token == "" {
+		return domain.ErrBadRequest
+	}
+
+	return u.sessionRepo.DeleteOtherSessions(id, token)
+}
+
+func sessionPolicy(rememberMe bool) domain.SessionPolicy {
+	if rememberMe {
+		return domain.RememberMeSessionPolicy
+	}
+
+	return domain.BrowserSessionPolicy
+}
+
+// sessionLifetime returns the idle timeout and the absolute lifetime of
+// sessions created under the policy.
+func (u *authUsecase) sessionLifetime(policy domain.SessionPolicy) (time.Duration, time.Duration) {
+	if policy == domain.RememberMeSessionPolicy {
+		return u.params.RememberMeIdleTimeout, u.params.RememberMeMaxLifetime
+	}
+
+	return u.params.SessionIdleTimeout, u.params.SessionMaxLifetime
+}
+
+// enforceSessionLimit makes room for one more session of the user, evicting
+// the oldest sessions or rejecting the login depending on the limit policy.
+func (u *authUsecase) enforceSessionLimit(user domain.User) error {
+	limit := u.params.SessionLimit
+	if l, ok := u.params.SessionLimitByRole[strings.ToLower(user.Role)]; ok {
+		limit = l
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	ids, err := u.sessionRepo.GetSessionIDs(user.ID)
+	if err != nil {
+		return err
+	}
+	if len(ids) < limit {
+		return nil
+	}
+
+	if u.params.SessionLimitPolicy == domain.RejectSessionLimitPolicy {
+		return domain.ErrTooManySessions
+	}
+
+	for _, id := range ids[:len(ids)-limit+1] {
+		if err = u.sessionRepo.DeleteSession(user.ID, id); err != nil {
+			return err
+		}
+		logs.Logger.Info("session limit reached, evicted oldest session of user ", user.ID)
+	}
+
+	return nil
+}
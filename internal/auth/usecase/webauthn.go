@@ -0,0 +1,305 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	webAuthnRegistrationKey = "registration:"
+	webAuthnLoginKey        = "login:"
+)
+
+// webAuthnUser adapts domain.User to the webauthn.User interface,
+// user handle is the decimal user ID.
+type webAuthnUser struct {
+	user  domain.User
+	creds []webauthn.Credential
+}
+
+func (w *webAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.Itoa(w.user.ID))
+}
+
+func (w *webAuthnUser) WebAuthnName() string {
+	return w.user.Email
+}
+
+func (w *webAuthnUser) WebAuthnDisplayName() string {
+	return w.user.Name + " " + w.user.Surname
+}
+
+func (w *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return w.creds
+}
+
+func (w *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+func newWebAuthn(params domain.AuthParams) *webauthn.WebAuthn {
+	if params.WebAuthnRPID == "" {
+		return nil
+	}
+
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          params.WebAuthnRPID,
+		RPDisplayName: params.WebAuthnRPName,
+		RPOrigins:     params.WebAuthnRPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login: webauthn.TimeoutConfig{
+				Enforce:    true,
+				Timeout:    params.WebAuthnTimeout,
+				TimeoutUVD: params.WebAuthnTimeout,
+			},
+			Registration: webauthn.TimeoutConfig{
+				Enforce:    true,
+				Timeout:    params.WebAuthnTimeout,
+				TimeoutUVD: params.WebAuthnTimeout,
+			},
+		},
+	})
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/usecase", "newWebAuthn", err, "WebAuthn is disabled")
+		return nil
+	}
+
+	return wa
+}
+
+func (u *authUsecase) BeginWebAuthnRegistration(id int) (*protocol.CredentialCreation, error) {
+	if u.webAuthn == nil {
+		return nil, domain.ErrFeatureDisabled
+	}
+	if id == 0 {
+		return nil, domain.ErrBadRequest
+	}
+
+	user, err := u.webAuthnUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.creds))
+	for _, c := range user.creds {
+		exclusions = append(exclusions, c.Descriptor())
+	}
+
+	creation, session, err := u.webAuthn.BeginRegistration(
+		user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.saveWebAuthnSession(webAuthnRegistrationKey+strconv.Itoa(id), session); err != nil {
+		return nil, err
+	}
+
+	return creation, nil
+}
+
+func (u *authUsecase) FinishWebAuthnRegistration(id int, response *protocol.ParsedCredentialCreationData) error {
+	if u.webAuthn == nil {
+		return domain.ErrFeatureDisabled
+	}
+	if id == 0 || response == nil {
+		return domain.ErrBadRequest
+	}
+
+	session, err := u.loadWebAuthnSession(webAuthnRegistrationKey + strconv.Itoa(id))
+	if err != nil {
+		return err
+	}
+
+	user, err := u.webAuthnUser(id)
+	if err != nil {
+		return err
+	}
+
+	cred, err := u.webAuthn.CreateCredential(user, session, response)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/usecase", "FinishWebAuthnRegistration", err, "attestation is invalid")
+		return domain.ErrWrongCredentials
+	}
+
+	if err = u.authRepo.AddWebAuthnCredential(toDomainCredential(id, *cred)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *authUsecase) BeginWebAuthnLogin() (*protocol.CredentialAssertion, string, error) {
+	if u.webAuthn == nil {
+		return nil, "", domain.ErrFeatureDisabled
+	}
+
+	assertion, session, err := u.webAuthn.BeginDiscoverableLogin(
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := generateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = u.saveWebAuthnSession(webAuthnLoginKey+key, session); err != nil {
+		return nil, "", err
+	}
+
+	return assertion, key, nil
+}
+
+func (u *authUsecase) FinishWebAuthnLogin(key string, response *protocol.ParsedCredentialAssertionData, rememberMe bool) (domain.Session, int, error) {
+	if u.webAuthn == nil {
+		return domain.Session{}, 0, domain.ErrFeatureDisabled
+	}
+	if key == "" || response == nil {
+		return domain.Session{}, 0, domain.ErrBadRequest
+	}
+
+	session, err := u.loadWebAuthnSession(webAuthnLoginKey + key)
+	if err != nil {
+		return domain.Session{}, 0, err
+	}
+
+	var user *webAuthnUser
+	cred, err := u.webAuthn.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		ownerID, err := u.authRepo.GetWebAuthnCredentialOwner(rawID)
+		if err != nil {
+			return nil, err
+		}
+		if strconv.Itoa(ownerID) != string(userHandle) {
+			return nil, domain.ErrWrongCredentials
+		}
+
+		user, err = u.webAuthnUser(ownerID)
+		return user, err
+	}, session, response)
+	if err != nil {
+		logs.LogError(logs.Logger, "auth/usecase", "FinishWebAuthnLogin", err, "assertion is invalid")
+		return domain.Session{}, 0, domain.ErrWrongCredentials
+	}
+
+	if cred.Authenticator.CloneWarning {
+		return domain.Session{}, 0, domain.ErrWrongCredentials
+	}
+
+	if err = u.authRepo.UpdateWebAuthnCredential(toDomainCredential(user.user.ID, *cred)); err != nil {
+		return domain.Session{}, 0, err
+	}
+
+	if !user.user.Confirmed {
+		return domain.Session{}, 0, domain.ErrUnconfirmedUser
+	}
+
+	s, err := u.createSession(user.user, sessionPolicy(rememberMe))
+	if err != nil {
+		return domain.Session{}, 0, err
+	}
+
+	return s, user.user.ID, nil
+}
+
+func (u *authUsecase) webAuthnUser(id int) (*webAuthnUser, error) {
+	user, err := u.authRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := u.authRepo.GetWebAuthnCredentials(id)
+	if err != nil {
+		return nil, err
+	}
+
+	wu := &webAuthnUser{user: user}
+	for _, c := range creds {
+		wu.creds = append(wu.creds, toWebAuthnCredential(c))
+	}
+
+	return wu, nil
+}
+
+func (u *authUsecase) saveWebAuthnSession(key string, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	return u.sessionRepo.AddWebAuthnSession(key, data, u.params.WebAuthnTimeout)
+}
+
+func (u *authUsecase) loadWebAuthnSession(key string) (webauthn.SessionData, error) {
+	data, err := u.sessionRepo.ConsumeWebAuthnSession(key)
+	if err != nil {
+		return webauthn.SessionData{}, err
+	}
+
+	var session webauthn.SessionData
+	if err = json.Unmarshal(data, &session); err != nil {
+		return webauthn.SessionData{}, errors.Join(domain.ErrInvalidToken, err)
+	}
+
+	// ValidateDiscoverableLogin does not look at the deadline, so it is
+	// checked here for every ceremony instead of relying on the key TTL.
+	if !session.Expires.IsZero() && session.Expires.Before(time.Now()) {
+		return webauthn.SessionData{}, domain.ErrInvalidToken
+	}
+
+	return session, nil
+}
+
+func toWebAuthnCredential(c domain.WebAuthnCredential) webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+	for _, t := range c.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(t))
+	}
+
+	return webauthn.Credential{
+		ID:              c.ID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: c.BackupEligible,
+			BackupState:    c.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    c.AAGUID,
+			SignCount: c.SignCount,
+		},
+	}
+}
+
+func toDomainCredential(userID int, c webauthn.Credential) domain.WebAuthnCredential {
+	transports := make([]string, 0, len(c.Transport))
+	for _, t := range c.Transport {
+		transports = append(transports, string(t))
+	}
+
+	return domain.WebAuthnCredential{
+		ID:              c.ID,
+		UserID:          userID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		AAGUID:          c.Authenticator.AAGUID,
+		SignCount:       c.Authenticator.SignCount,
+		Transports:      transports,
+		BackupEligible:  c.Flags.BackupEligible,
+		BackupState:     c.Flags.BackupState,
+	}
+}
@@ -0,0 +1,434 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+)
+
+const (
+	testRPID   = "localhost"
+	testOrigin = "https://localhost"
+)
+
+// softAuthenticator is a software passkey: a P-256 key producing "none"
+// attestations and assertions the way a browser would hand them over.
+type softAuthenticator struct {
+	key        *ecdsa.PrivateKey
+	id         []byte
+	userHandle []byte
+	counter    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, userID string) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := make([]byte, 16)
+	if _, err = rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+
+	return &softAuthenticator{key: key, id: id, userHandle: []byte(userID)}
+}
+
+func (a *softAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.counter)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, typ string, challenge protocol.URLEncodedBase64) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": challenge.String(),
+		"origin":    testOrigin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func (a *softAuthenticator) create(t *testing.T, creation *protocol.CredentialCreation) *protocol.ParsedCredentialCreationData {
+	t.Helper()
+
+	publicKey, err := webauthncbor.Marshal(map[int]interface{}{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attested := make([]byte, 16) // zero AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.id)))
+	attested = append(attested, a.id...)
+	attested = append(attested, publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(0x45, attested), // UP, UV, AT
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := a.body(t, map[string]string{
+		"clientDataJSON":    encode(a.clientData(t, "webauthn.create", creation.Response.Challenge)),
+		"attestationObject": encode(attestation),
+	})
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parsed
+}
+
+func (a *softAuthenticator) get(t *testing.T, assertion *protocol.CredentialAssertion) *protocol.ParsedCredentialAssertionData {
+	t.Helper()
+
+	authData := a.authData(0x05, nil) // UP, UV
+	clientData := a.clientData(t, "webauthn.get", assertion.Response.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := a.body(t, map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.userHandle),
+	})
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parsed
+}
+
+func (a *softAuthenticator) body(t *testing.T, response map[string]string) *bytes.Reader {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       encode(a.id),
+		"rawId":    encode(a.id),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.NewReader(data)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+type webAuthnAuthRepo struct {
+	domain.AuthRepository
+	users map[int]domain.User
+	creds map[string]domain.WebAuthnCredential
+}
+
+func (r *webAuthnAuthRepo) GetByID(id int) (domain.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return domain.User{}, domain.ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (r *webAuthnAuthRepo) AddWebAuthnCredential(cred domain.WebAuthnCredential) error {
+	r.creds[string(cred.ID)] = cred
+	return nil
+}
+
+func (r *webAuthnAuthRepo) GetWebAuthnCredentials(userID int) ([]domain.WebAuthnCredential, error) {
+	var creds []domain.WebAuthnCredential
+	for _, c := range r.creds {
+		if c.UserID == userID {
+			creds = append(creds, c)
+		}
+	}
+
+	return creds, nil
+}
+
+func (r *webAuthnAuthRepo) GetWebAuthnCredentialOwner(credentialID []byte) (int, error) {
+	cred, ok := r.creds[string(credentialID)]
+	if !ok {
+		return 0, domain.ErrNotFound
+	}
+
+	return cred.UserID, nil
+}
+
+func (r *webAuthnAuthRepo) UpdateWebAuthnCredential(cred domain.WebAuthnCredential) error {
+	r.creds[string(cred.ID)] = cred
+	return nil
+}
+
+// webAuthnSessionRepo keeps ceremonies without a TTL, so expiry is up to
+// the challenge deadline checked by the usecase.
+type webAuthnSessionRepo struct {
+	domain.SessionRepository
+	ceremonies map[string][]byte
+	sessions   []domain.Session
+}
+
+func (r *webAuthnSessionRepo) AddWebAuthnSession(key string, data []byte, _ time.Duration) error {
+	r.ceremonies[key] = data
+	return nil
+}
+
+func (r *webAuthnSessionRepo) ConsumeWebAuthnSession(key string) ([]byte, error) {
+	data, ok := r.ceremonies[key]
+	if !ok {
+		return nil, domain.ErrInvalidToken
+	}
+	delete(r.ceremonies, key)
+
+	return data, nil
+}
+
+func (r *webAuthnSessionRepo) Add(session domain.Session) error {
+	r.sessions = append(r.sessions, session)
+	return nil
+}
+
+func (r *webAuthnSessionRepo) AddRefreshToken(domain.RefreshToken) error {
+	return nil
+}
+
+func newWebAuthnUsecase(t *testing.T, timeout time.Duration) (domain.AuthUsecase, *webAuthnAuthRepo, *webAuthnSessionRepo) {
+	t.Helper()
+
+	key, err := LoadSigningKey("HS256", "", "test", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authRepo := &webAuthnAuthRepo{
+		users: map[int]domain.User{1: {ID: 1, Email: "user@example.com", Confirmed: true}},
+		creds: map[string]domain.WebAuthnCredential{},
+	}
+	sessionRepo := &webAuthnSessionRepo{ceremonies: map[string][]byte{}}
+
+	u := NewAuthUsecase(authRepo, sessionRepo, nil, NewStaticKeyRing(key), domain.AuthParams{
+		WebAuthnRPID:       testRPID,
+		WebAuthnRPName:     "AtomHack",
+		WebAuthnRPOrigins:  []string{testOrigin},
+		WebAuthnTimeout:    timeout,
+		AccessTokenTTL:     time.Minute,
+		SessionIdleTimeout: time.Hour,
+		SessionMaxLifetime: time.Hour,
+	})
+
+	return u, authRepo, sessionRepo
+}
+
+func register(t *testing.T, u domain.AuthUsecase, a *softAuthenticator) {
+	t.Helper()
+
+	creation, err := u.BeginWebAuthnRegistration(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = u.FinishWebAuthnRegistration(1, a.create(t, creation)); err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+}
+
+func login(t *testing.T, u domain.AuthUsecase, a *softAuthenticator) (domain.Session, error) {
+	t.Helper()
+
+	assertion, key, err := u.BeginWebAuthnLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _, err := u.FinishWebAuthnLogin(key, a.get(t, assertion), false)
+	return s, err
+}
+
+func TestWebAuthnRegistrationAndLogin(t *testing.T) {
+	u, authRepo, sessionRepo := newWebAuthnUsecase(t, time.Minute)
+	a := newSoftAuthenticator(t, "1")
+
+	register(t, u, a)
+
+	cred, ok := authRepo.creds[string(a.id)]
+	if !ok || cred.UserID != 1 {
+		t.Fatalf("credential is not stored for the user: %+v", authRepo.creds)
+	}
+
+	a.counter = 1
+	s, err := login(t, u, a)
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if s.UserID != 1 || len(sessionRepo.sessions) != 1 {
+		t.Fatalf("session is not created: %+v", s)
+	}
+}
+
+func TestWebAuthnRegistrationRejectsOtherUserCeremony(t *testing.T) {
+	u, authRepo, _ := newWebAuthnUsecase(t, time.Minute)
+	authRepo.users[2] = domain.User{ID: 2, Email: "other@example.com", Confirmed: true}
+	a := newSoftAuthenticator(t, "2")
+
+	creation, err := u.BeginWebAuthnRegistration(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = u.FinishWebAuthnRegistration(2, a.create(t, creation))
+	if !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestWebAuthnChallengeExpires(t *testing.T) {
+	u, _, _ := newWebAuthnUsecase(t, 100*time.Millisecond)
+	a := newSoftAuthenticator(t, "1")
+
+	register(t, u, a)
+
+	assertion, key, err := u.BeginWebAuthnLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	a.counter = 1
+	_, _, err = u.FinishWebAuthnLogin(key, a.get(t, assertion), false)
+	if !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestWebAuthnRegistrationChallengeExpires(t *testing.T) {
+	u, authRepo, _ := newWebAuthnUsecase(t, 100*time.Millisecond)
+	a := newSoftAuthenticator(t, "1")
+
+	creation, err := u.BeginWebAuthnRegistration(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	err = u.FinishWebAuthnRegistration(1, a.create(t, creation))
+	if !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+	if len(authRepo.creds) != 0 {
+		t.Fatalf("expired ceremony stored a credential")
+	}
+}
+
+func TestWebAuthnChallengeIsSingleUse(t *testing.T) {
+	u, _, _ := newWebAuthnUsecase(t, time.Minute)
+	a := newSoftAuthenticator(t, "1")
+
+	register(t, u, a)
+
+	assertion, key, err := u.BeginWebAuthnLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.counter = 1
+	response := a.get(t, assertion)
+	if _, _, err = u.FinishWebAuthnLogin(key, response, false); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	_, _, err = u.FinishWebAuthnLogin(key, response, false)
+	if !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken on replay, got %v", err)
+	}
+}
+
+func TestWebAuthnSignCountIncreases(t *testing.T) {
+	u, authRepo, _ := newWebAuthnUsecase(t, time.Minute)
+	a := newSoftAuthenticator(t, "1")
+
+	register(t, u, a)
+
+	for _, counter := range []uint32{1, 5, 6} {
+		a.counter = counter
+		if _, err := login(t, u, a); err != nil {
+			t.Fatalf("login with counter %d failed: %v", counter, err)
+		}
+
+		if got := authRepo.creds[string(a.id)].SignCount; got != counter {
+			t.Fatalf("stored sign count is %d, want %d", got, counter)
+		}
+	}
+}
+
+func TestWebAuthnCloneWarning(t *testing.T) {
+	u, authRepo, sessionRepo := newWebAuthnUsecase(t, time.Minute)
+	a := newSoftAuthenticator(t, "1")
+
+	register(t, u, a)
+
+	a.counter = 10
+	if _, err := login(t, u, a); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	for _, counter := range []uint32{10, 3} {
+		a.counter = counter
+		if _, err := login(t, u, a); !errors.Is(err, domain.ErrWrongCredentials) {
+			t.Fatalf("counter %d: expected ErrWrongCredentials, got %v", counter, err)
+		}
+	}
+
+	if got := authRepo.creds[string(a.id)].SignCount; got != 10 {
+		t.Fatalf("stored sign count is %d after a clone warning, want 10", got)
+	}
+	if len(sessionRepo.sessions) != 1 {
+		t.Fatalf("a cloned authenticator got a session: %d sessions", len(sessionRepo.sessions))
+	}
+}
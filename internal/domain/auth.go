@@ -2,6 +2,8 @@ package domain
 
 import (
 	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
 )
 
 type Role int
@@ -21,10 +23,36 @@ type SessionContext struct {
 }
 
 type Credentials struct {
-	Password []byte `json:"password"`
-	Email    string `json:"email"`
+	Password   []byte `json:"password"`
+	Email      string `json:"email"`
+	RememberMe bool   `json:"rememberMe"`
+	IP         string `json:"-"`
+}
+
+// LoginBlock is a pause imposed on login attempts after failures.
+type LoginBlock struct {
+	// Locked is set for a lockout, otherwise it is an exponential delay.
+	Locked     bool
+	RetryAfter time.Duration
 }
 
+// SessionPolicy decides how long a session lives and whether its cookies
+// outlive the browser.
+type SessionPolicy string
+
+const (
+	BrowserSessionPolicy    SessionPolicy = "browser"
+	RememberMeSessionPolicy SessionPolicy = "remember_me"
+)
+
+// SessionLimitPolicy decides what happens to a login over the session limit.
+type SessionLimitPolicy string
+
+const (
+	EvictOldestSessionLimitPolicy SessionLimitPolicy = "evict_oldest"
+	RejectSessionLimitPolicy      SessionLimitPolicy = "reject"
+)
+
 type User struct {
 	ID         int    `json:"id"`
 	Email      string `json:"email"`
@@ -37,9 +65,77 @@ type User struct {
 }
 
 type Session struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expiresAt"`
-	UserID    int       `json:"-"`
+	Token            string        `json:"token"`
+	ExpiresAt        time.Time     `json:"expiresAt"`
+	UserID           int           `json:"-"`
+	RefreshToken     string        `json:"refreshToken,omitempty"`
+	RefreshExpiresAt time.Time     `json:"refreshExpiresAt,omitempty"`
+	Policy           SessionPolicy `json:"-"`
+}
+
+// SessionInfo describes a login of the user on some device.
+type SessionInfo struct {
+	ID        string        `json:"id"`
+	CreatedAt time.Time     `json:"createdAt"`
+	LastSeen  time.Time     `json:"lastSeen"`
+	IP        string        `json:"ip"`
+	UserAgent string        `json:"userAgent"`
+	Policy    SessionPolicy `json:"policy"`
+	ExpiresAt time.Time     `json:"expiresAt"`
+	Current   bool          `json:"current"`
+}
+
+type TokenClaims struct {
+	ID        string
+	UserID    int
+	Email     string
+	Role      string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// TokenIntrospection is the RFC 7662 introspection response.
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Email     string `json:"email,omitempty"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type RefreshToken struct {
+	Token       string
+	UserID      int
+	Family      string
+	AccessToken string
+	ExpiresAt   time.Time
+	// SessionExpiresAt is the absolute end of the session, refresh tokens of
+	// the family are never issued past it.
+	SessionExpiresAt time.Time
+	Policy           SessionPolicy
+	IdleTimeout      time.Duration
 }
 
 type SMTPParams struct {
@@ -51,20 +147,181 @@ type SMTPParams struct {
 	DestPassword    string
 }
 
+type AuthParams struct {
+	ConfirmCodeTTL        time.Duration
+	ConfirmResendCooldown time.Duration
+	ConfirmMaxAttempts    int
+	ResetTokenTTL         time.Duration
+	MFAIssuer             string
+	MFAEncryptionKey      []byte
+	MFAChallengeTTL       time.Duration
+	MFAMaxAttempts        int
+	WebAuthnRPID          string
+	WebAuthnRPName        string
+	WebAuthnRPOrigins     []string
+	WebAuthnTimeout       time.Duration
+	MagicLinkEnabled      bool
+	MagicLinkTTL          time.Duration
+	MagicLinkSecret       []byte
+	AccessTokenTTL        time.Duration
+	SessionIdleTimeout    time.Duration
+	SessionMaxLifetime    time.Duration
+	RememberMeIdleTimeout time.Duration
+	RememberMeMaxLifetime time.Duration
+	SessionLimit          int
+	SessionLimitByRole    map[string]int
+	SessionLimitPolicy    SessionLimitPolicy
+	LoginFailureWindow    time.Duration
+	LoginBackoffBase      time.Duration
+	LoginBackoffMax       time.Duration
+	LoginLockoutThreshold int
+	LoginLockoutDuration  time.Duration
+	LoginIPThreshold      int
+	AntiEnumeration       bool
+	PasswordMinLength     int
+	PasswordMaxLength     int
+	PasswordMinClasses    int
+	PasswordMinScore      int
+	PasswordBreachFilter  string
+	PasswordHashTime      int
+	PasswordHashMemory    int
+	PasswordHashThreads   int
+	PasswordPepperID      string
+	PasswordPeppers       map[string][]byte
+	JWTIssuer             string
+	JWTAudience           string
+	JWTAlgorithm          string
+	JWTPrivateKeyFile     string
+	JWTKeyID              string
+	JWTKeysDir            string
+	JWTKeysReload         time.Duration
+	OAuthClients          map[string]string
+	OAuthScope            string
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// ConfirmPair identifies the user by ID or, when registration does not
+// return the ID, by email.
 type ConfirmPair struct {
-	ID   int    `json:"id"`
+	ID    int    `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+	Code  string `json:"code"`
+}
+
+type ConfirmResend struct {
+	ID    int    `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordReset struct {
+	Token    string `json:"token"`
+	Password []byte `json:"password"`
+}
+
+type PasswordChange struct {
+	OldPassword         []byte `json:"oldPassword"`
+	NewPassword         []byte `json:"newPassword"`
+	RevokeOtherSessions bool   `json:"revokeOtherSessions"`
+}
+
+type TOTP struct {
+	UserID  int
+	Secret  []byte
+	Enabled bool
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TOTPCode struct {
 	Code string `json:"code"`
 }
 
+// MFAChallenge is the first factor that has been passed, waiting for the second.
+type MFAChallenge struct {
+	UserID int
+	Policy SessionPolicy
+}
+
+type MFALogin struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+// RecoveryCode is a stored recovery code, Lookup is a keyed hash of the code
+// that finds the one argon2id hash to verify.
+type RecoveryCode struct {
+	ID     int
+	Lookup string
+	Hash   []byte
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+type MagicLinkLogin struct {
+	Token      string `json:"token"`
+	RememberMe bool   `json:"rememberMe"`
+}
+
+type WebAuthnCredential struct {
+	ID              []byte
+	UserID          int
+	PublicKey       []byte
+	AttestationType string
+	AAGUID          []byte
+	SignCount       uint32
+	Transports      []string
+	BackupEligible  bool
+	BackupState     bool
+}
+
 type AuthUsecase interface {
 	Login(credentials Credentials) (Session, int, error)
 	Logout(token string) error
 	Register(user User) (int, error)
 	GetUserID(token string) (string, error)
-	GenerateJWT(email string) (string, error)
+	GenerateJWT(user User, expiresAt time.Time) (string, error)
+	ParseJWT(token string) (TokenClaims, error)
+	JWKS() JWKS
+	AuthenticateClient(id, secret string) error
+	Introspect(token string) (TokenIntrospection, error)
+	RevokeToken(token, hint string) error
+	TouchSession(token, ip, userAgent string) (SessionInfo, error)
+	GetSessions(id int, token string) ([]SessionInfo, error)
+	DeleteSession(id int, sessionID string) error
+	DeleteOtherSessions(id int, token string) error
 	GetByID(id int) (User, error)
 	AddCodeByID(id int, code string) error
 	ConfirmUser(pair ConfirmPair) (Session, error)
+	ResendCode(resend ConfirmResend, code string) (string, error)
+	ForgotPassword(email string) (string, error)
+	ResetPassword(reset PasswordReset) error
+	ChangePassword(id int, token string, change PasswordChange) error
+	EnrollTOTP(id int) (TOTPEnrollment, error)
+	ActivateTOTP(id int, code string) ([]string, error)
+	DisableTOTP(id int, code string) error
+	LoginMFA(login MFALogin) (Session, int, error)
+	RegenerateRecoveryCodes(id int, code string) ([]string, error)
+	CountRecoveryCodes(id int) (int, error)
+	BeginWebAuthnRegistration(id int) (*protocol.CredentialCreation, error)
+	FinishWebAuthnRegistration(id int, response *protocol.ParsedCredentialCreationData) error
+	BeginWebAuthnLogin() (*protocol.CredentialAssertion, string, error)
+	FinishWebAuthnLogin(key string, response *protocol.ParsedCredentialAssertionData, rememberMe bool) (Session, int, error)
+	RequestMagicLink(email string, nonce string) (string, error)
+	LoginMagicLink(token string, nonce string, rememberMe bool) (Session, int, error)
+	Refresh(refreshToken string) (Session, int, error)
 }
 
 type AuthRepository interface {
@@ -73,12 +330,55 @@ type AuthRepository interface {
 	AddUser(user User) (int, error)
 	UserExists(email string) (bool, error)
 	ConfirmUser(id int) (string, error)
+	GetPasswordByID(id int) ([]byte, error)
+	UpdatePassword(id int, password []byte) error
+	GetTOTP(userID int) (TOTP, error)
+	SetTOTPSecret(userID int, secret []byte) error
+	EnableTOTP(userID int) error
+	DeleteTOTP(userID int) error
+	ReplaceRecoveryCodes(userID int, codes []RecoveryCode) error
+	GetRecoveryCodes(userID int, lookup string) ([]RecoveryCode, error)
+	UseRecoveryCode(id int) (bool, error)
+	CountRecoveryCodes(userID int) (int, error)
+	AddWebAuthnCredential(cred WebAuthnCredential) error
+	GetWebAuthnCredentials(userID int) ([]WebAuthnCredential, error)
+	GetWebAuthnCredentialOwner(credentialID []byte) (int, error)
+	UpdateWebAuthnCredential(cred WebAuthnCredential) error
 }
 
 type SessionRepository interface {
 	Add(session Session) error
 	DeleteByToken(token string) error
 	GetUserID(token string) (string, error)
-	AddCodeByID(id int, code string) error
+	AddCodeByID(id int, code string, ttl time.Duration) error
 	GetCodeByID(id string) (string, error)
+	DeleteCodeByID(id int) error
+	IncrCodeAttempts(id int, ttl time.Duration) (int, error)
+	SetCodeCooldown(id int, cooldown time.Duration) (bool, error)
+	DeleteByUserID(id int) error
+	DeleteOtherSessions(id int, token string) error
+	AddResetToken(token string, userID int, ttl time.Duration) error
+	GetResetToken(token string) (int, error)
+	ConsumeResetToken(token string) (int, error)
+	AddMFAChallenge(token string, challenge MFAChallenge, ttl time.Duration) error
+	GetMFAChallenge(token string) (MFAChallenge, error)
+	DeleteMFAChallenge(token string) error
+	IncrMFAChallengeAttempts(token string, ttl time.Duration) (int, error)
+	MarkTOTPUsed(userID int, step int64, ttl time.Duration) (bool, error)
+	AddWebAuthnSession(key string, data []byte, ttl time.Duration) error
+	ConsumeWebAuthnSession(key string) ([]byte, error)
+	AddMagicLink(id string, userID int, nonceHash string, ttl time.Duration) error
+	ConsumeMagicLink(id string) (int, string, error)
+	AddRefreshToken(rt RefreshToken) error
+	UseRefreshToken(token string) (RefreshToken, bool, error)
+	GetRefreshToken(token string) (RefreshToken, error)
+	TouchSession(token, ip, userAgent string) (SessionInfo, error)
+	GetSessions(id int, token string) ([]SessionInfo, error)
+	GetSessionIDs(id int) ([]string, error)
+	DeleteSession(id int, sessionID string) error
+	RevokeRefreshFamily(family string) error
+	RegisterLoginFailure(email string, ip string, window time.Duration) (int, int, error)
+	BlockLogin(email string, ip string, account LoginBlock, byIP LoginBlock) error
+	GetLoginBlock(email string, ip string) (LoginBlock, error)
+	ResetLoginFailures(email string) error
 }
@@ -3,6 +3,7 @@ package domain
 import (
 	"errors"
 	"net/http"
+	"time"
 )
 
 const DateOutOfRangeErrCode = "23514"
@@ -17,8 +18,63 @@ var (
 	ErrAlreadyExists       = errors.New("resource already exists")
 	ErrOutOfRange          = errors.New("id is out of range")
 	ErrUnconfirmedUser     = errors.New("user isnt confirmed")
+	ErrInvalidCode         = errors.New("confirmation code is invalid or expired")
+	ErrTooManyRequests     = errors.New("too many requests, try again later")
+	ErrMFARequired         = errors.New("second factor is required")
+	ErrFeatureDisabled     = errors.New("feature is disabled")
+	ErrInvalidClient       = errors.New("client authentication failed")
+	ErrTooManySessions     = errors.New("session limit is reached, log out on another device")
+	ErrAccountLocked       = errors.New("account is temporarily locked after failed logins")
+	ErrWeakPassword        = errors.New("password does not satisfy password policy")
 )
 
+// Rules of the password policy, reported to the client with violations.
+const (
+	PasswordRuleMinLength        = "min_length"
+	PasswordRuleMaxLength        = "max_length"
+	PasswordRuleCharacterClasses = "character_classes"
+	PasswordRulePersonalInfo     = "personal_info"
+	PasswordRuleStrength         = "strength"
+	PasswordRuleBreached         = "breached"
+)
+
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule the password violates. It wraps
+// ErrWeakPassword.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return ErrWeakPassword.Error()
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
+
+// LoginBlockedError rejects a login attempt until RetryAfter has passed. It
+// wraps ErrAccountLocked or ErrTooManyRequests.
+type LoginBlockedError struct {
+	Err        error
+	RetryAfter time.Duration
+	// Notify is set on the attempt that locked the account, so that the
+	// owner is told about it once.
+	Notify bool
+}
+
+func (e *LoginBlockedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return e.Err
+}
+
 func GetStatusCode(err error) int {
 	if err == nil {
 		return http.StatusOK
@@ -41,6 +97,22 @@ func GetStatusCode(err error) int {
 		return http.StatusConflict
 	case errors.Is(err, ErrUnconfirmedUser):
 		return http.StatusForbidden
+	case errors.Is(err, ErrInvalidCode):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrMFARequired):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrFeatureDisabled):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidClient):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrTooManySessions):
+		return http.StatusConflict
+	case errors.Is(err, ErrAccountLocked):
+		return http.StatusLocked
+	case errors.Is(err, ErrWeakPassword):
+		return http.StatusBadRequest
 	default:
 		return http.StatusInternalServerError
 	}
@@ -2,14 +2,17 @@ package domain
 
 import (
 	"encoding/json"
+	"errors"
 	logs "github.com/certified-juniors/AtomHack/internal/logger"
 	"io"
+	"net"
 	"net/http"
 )
 
 type Response struct {
-	Body interface{} `json:"body,omitempty"`
-	Err  string      `json:"err,omitempty"`
+	Body       interface{}         `json:"body,omitempty"`
+	Err        string              `json:"err,omitempty"`
+	Violations []PasswordViolation `json:"violations,omitempty"`
 }
 
 func WriteError(w http.ResponseWriter, errString string, status int) {
@@ -17,6 +20,19 @@ func WriteError(w http.ResponseWriter, errString string, status int) {
 	json.NewEncoder(w).Encode(&Response{Err: errString})
 }
 
+// WriteUsecaseError writes the error with its status code, a password policy
+// error comes with the list of violated rules.
+func WriteUsecaseError(w http.ResponseWriter, err error) {
+	var policyErr *PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		w.WriteHeader(GetStatusCode(err))
+		json.NewEncoder(w).Encode(&Response{Err: err.Error(), Violations: policyErr.Violations})
+		return
+	}
+
+	WriteError(w, err.Error(), GetStatusCode(err))
+}
+
 func WriteResponse(w http.ResponseWriter, result map[string]interface{}, status int) {
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(&Response{Body: result})
@@ -28,3 +44,14 @@ func CloseAndAlert(body io.ReadCloser, packageName, funcName string) {
 		logs.LogError(logs.Logger, packageName, funcName, err, err.Error())
 	}
 }
+
+// ClientIP returns the address of the connected peer. Forwarding headers are
+// not trusted as the service is exposed directly.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimit allows Requests per sliding Window.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the window frees a slot again.
+	Reset time.Duration
+}
+
+// RateLimitStore counts requests by key. It is shared by all instances of
+// the service, or local to one instance.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
@@ -0,0 +1,13 @@
+package env
+
+import "os"
+
+// String returns the environment variable key, def when it is unset or
+// empty.
+func String(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	return def
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
 	"net/http"
 	"time"
 )
@@ -42,6 +43,24 @@ func (m *AuthMiddleware) IsAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		// sliding expiry: activity moves the end of the session and the
+		// cookie, browser sessions keep cookies without Expires
+		session, err := m.authUsecase.TouchSession(sessionToken, domain.ClientIP(r), r.UserAgent())
+		if err != nil {
+			logs.LogError(logs.Logger, "middleware", "IsAuth", err, "Failed to extend session")
+		}
+		if session.Policy == domain.RememberMeSessionPolicy && !session.ExpiresAt.IsZero() {
+			http.SetCookie(w, &http.Cookie{
+				Name:     "session_token",
+				Value:    sessionToken,
+				Expires:  session.ExpiresAt,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteNoneMode,
+				Secure:   true,
+			})
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
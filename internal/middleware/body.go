@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// maxRequestBody is far above any JSON the API accepts.
+const maxRequestBody = 1 << 20
+
+// LimitBody fails reading of request bodies larger than maxRequestBody, so
+// that handlers and the rate limiter never buffer arbitrary amounts.
+func LimitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
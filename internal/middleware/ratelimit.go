@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+	logs "github.com/certified-juniors/AtomHack/internal/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultRateLimits cover every route that checks a secret or sends mail,
+// so that nothing is left unlimited when RATE_LIMITS is not set.
+var defaultRateLimits = map[string]domain.RateLimit{
+	"/api/v1/auth/login":                 {Requests: 10, Window: time.Minute},
+	"/api/v1/auth/login/mfa":             {Requests: 10, Window: time.Minute},
+	"/api/v1/auth/register":              {Requests: 5, Window: 10 * time.Minute},
+	"/api/v1/auth/confirm":               {Requests: 10, Window: 10 * time.Minute},
+	"/api/v1/auth/confirm/resend":        {Requests: 3, Window: 10 * time.Minute},
+	"/api/v1/auth/refresh":               {Requests: 30, Window: time.Minute},
+	"/api/v1/auth/password":              {Requests: 5, Window: 10 * time.Minute},
+	"/api/v1/auth/password/forgot":       {Requests: 3, Window: 10 * time.Minute},
+	"/api/v1/auth/password/reset":        {Requests: 5, Window: 10 * time.Minute},
+	"/api/v1/auth/magic-link":            {Requests: 3, Window: 10 * time.Minute},
+	"/api/v1/auth/magic-link/verify":     {Requests: 10, Window: 10 * time.Minute},
+	"/api/v1/auth/webauthn/login/begin":  {Requests: 20, Window: time.Minute},
+	"/api/v1/auth/webauthn/login/finish": {Requests: 10, Window: time.Minute},
+	"/oauth/introspect":                  {Requests: 60, Window: time.Minute},
+	"/oauth/revoke":                      {Requests: 60, Window: time.Minute},
+}
+
+// GetRateLimits returns the default limits overridden by RATE_LIMITS, a
+// comma separated list of route:requests/window pairs, e.g.
+// /api/v1/auth/login:10/1m. Zero requests turn the limit of a route off.
+// Routes are matched by their path template.
+func GetRateLimits() map[string]domain.RateLimit {
+	limits := make(map[string]domain.RateLimit, len(defaultRateLimits))
+	for route, limit := range defaultRateLimits {
+		limits[route] = limit
+	}
+
+	for _, v := range strings.Split(os.Getenv("RATE_LIMITS"), ",") {
+		route, limit, ok := strings.Cut(strings.TrimSpace(v), ":")
+		if !ok {
+			continue
+		}
+		requests, window, ok := strings.Cut(limit, "/")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(requests)
+		if err != nil || n < 0 {
+			continue
+		}
+		if n == 0 {
+			delete(limits, route)
+			continue
+		}
+		d, err := time.ParseDuration(window)
+		if err != nil || d <= 0 {
+			continue
+		}
+		limits[route] = domain.RateLimit{Requests: n, Window: d}
+	}
+
+	return limits
+}
+
+type RateLimiter struct {
+	store  domain.RateLimitStore
+	limits map[string]domain.RateLimit
+}
+
+func NewRateLimiter(store domain.RateLimitStore, limits map[string]domain.RateLimit) *RateLimiter {
+	return &RateLimiter{store: store, limits: limits}
+}
+
+// Limit counts requests to a limited route separately by client IP and by
+// the email from the JSON body, the stricter of the two is reported in
+// RateLimit-* headers. Routes without a limit are passed through. If the
+// store is unavailable requests are let through rather than rejected.
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, limit, ok := l.routeLimit(r)
+		if !ok || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		keys := []string{"ip:" + domain.ClientIP(r) + ":" + route}
+		if email := requestEmail(r); email != "" {
+			keys = append(keys, "email:"+email+":"+route)
+		}
+
+		var result domain.RateLimitResult
+		for i, key := range keys {
+			res, err := l.store.Allow(r.Context(), key, limit)
+			if err != nil {
+				logs.LogError(logs.Logger, "middleware", "Limit", err, "Failed to check rate limit")
+				next.ServeHTTP(w, r)
+				return
+			}
+			if i == 0 || !res.Allowed || (result.Allowed && res.Remaining < result.Remaining) {
+				result = res
+			}
+			if !res.Allowed {
+				break
+			}
+		}
+
+		reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+		w.Header().Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(int(limit.Window.Seconds())))
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", reset)
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", reset)
+			domain.WriteError(w, domain.ErrTooManyRequests.Error(), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *RateLimiter) routeLimit(r *http.Request) (string, domain.RateLimit, bool) {
+	current := mux.CurrentRoute(r)
+	if current == nil {
+		return "", domain.RateLimit{}, false
+	}
+	route, err := current.GetPathTemplate()
+	if err != nil {
+		return "", domain.RateLimit{}, false
+	}
+
+	limit, ok := l.limits[route]
+	return route, limit, ok
+}
+
+// requestEmail peeks at the email field of a JSON body and puts the body
+// back for the handler.
+func requestEmail(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err = json.Unmarshal(body, &req); err != nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(req.Email))
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+)
+
+// memoryRateLimitStore is a sliding window log local to the process, for a
+// single instance deployment.
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	lastSweep time.Time
+}
+
+type memoryWindow struct {
+	requests []time.Time
+	window   time.Duration
+}
+
+func NewMemoryRateLimitStore() domain.RateLimitStore {
+	return &memoryRateLimitStore{
+		windows:   make(map[string]*memoryWindow),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *memoryRateLimitStore) Allow(_ context.Context, key string, limit domain.RateLimit) (domain.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	w, ok := s.windows[key]
+	if !ok {
+		w = &memoryWindow{}
+		s.windows[key] = w
+	}
+	w.window = limit.Window
+	w.trim(now)
+
+	allowed := len(w.requests) < limit.Requests
+	if allowed {
+		w.requests = append(w.requests, now)
+	}
+
+	reset := limit.Window
+	if len(w.requests) > 0 {
+		reset = w.requests[0].Add(limit.Window).Sub(now)
+	}
+
+	return domain.RateLimitResult{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: max(limit.Requests-len(w.requests), 0),
+		Reset:     reset,
+	}, nil
+}
+
+// sweep drops keys without requests in their window once a minute, so that
+// the map does not grow with every client ever seen.
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, w := range s.windows {
+		if w.trim(now); len(w.requests) == 0 {
+			delete(s.windows, key)
+		}
+	}
+}
+
+func (w *memoryWindow) trim(now time.Time) {
+	i := 0
+	for i < len(w.requests) && !w.requests[i].After(now.Add(-w.window)) {
+		i++
+	}
+	w.requests = w.requests[i:]
+}
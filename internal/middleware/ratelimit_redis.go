@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitPrefix = "rate_limit:"
+
+// slidingWindow keeps a sorted set of request times in microseconds of the
+// Redis clock, so that instances with skewed clocks share one window. It
+// returns whether the request is allowed, the number of requests in the
+// window and the time in microseconds until the oldest one leaves it.
+var slidingWindow = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, now .. ':' .. ARGV[3])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+
+local reset = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+
+return {allowed, count, reset}
+`)
+
+type redisRateLimitStore struct {
+	client *redis.Client
+}
+
+func NewRedisRateLimitStore(client *redis.Client) domain.RateLimitStore {
+	return &redisRateLimitStore{client: client}
+}
+
+func (s *redisRateLimitStore) Allow(ctx context.Context, key string, limit domain.RateLimit) (domain.RateLimitResult, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return domain.RateLimitResult{}, err
+	}
+
+	values, err := slidingWindow.Run(ctx, s.client, []string{rateLimitPrefix + key},
+		limit.Window.Microseconds(), limit.Requests, hex.EncodeToString(nonce),
+	).Int64Slice()
+	if err != nil {
+		return domain.RateLimitResult{}, err
+	}
+
+	return domain.RateLimitResult{
+		Allowed:   values[0] == 1,
+		Limit:     limit.Requests,
+		Remaining: max(limit.Requests-int(values[1]), 0),
+		Reset:     time.Duration(values[2]) * time.Microsecond,
+	}, nil
+}
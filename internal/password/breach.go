@@ -0,0 +1,197 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BreachChecker tells whether a password is known from a data breach.
+type BreachChecker interface {
+	Breached(password []byte) bool
+}
+
+// BloomFilter is a compact set of SHA-1 hashes of breached passwords. A
+// password that is not in the set is never reported, one that is may be
+// reported falsely with the probability the filter was built for.
+type BloomFilter struct {
+	bits   []uint64
+	m      uint64
+	hashes uint64
+}
+
+var bloomMagic = [4]byte{'A', 'H', 'B', 'F'}
+
+var ErrInvalidFilter = errors.New("invalid breached password filter")
+
+// NewBloomFilter sizes a filter for n hashes with the false positive rate p.
+func NewBloomFilter(n uint64, p float64) *BloomFilter {
+	n = max(n, 1)
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+
+	return &BloomFilter{
+		bits:   make([]uint64, (m+63)/64),
+		m:      m,
+		hashes: max(k, 1),
+	}
+}
+
+func (f *BloomFilter) Breached(password []byte) bool {
+	return f.ContainsHash(sha1.Sum(password))
+}
+
+func (f *BloomFilter) AddHash(sum [sha1.Size]byte) {
+	h1, h2 := f.split(sum)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (f *BloomFilter) ContainsHash(sum [sha1.Size]byte) bool {
+	h1, h2 := f.split(sum)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// split takes two independent hashes from the SHA-1 for double hashing.
+func (f *BloomFilter) split(sum [sha1.Size]byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(sum[0:8]), binary.BigEndian.Uint64(sum[8:16]) | 1
+}
+
+// WriteTo stores the filter as magic, bit count, hash count and the bits,
+// all big endian.
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	header := make([]byte, 20)
+	copy(header, bloomMagic[:])
+	binary.BigEndian.PutUint64(header[4:], f.m)
+	binary.BigEndian.PutUint64(header[12:], f.hashes)
+	if _, err := bw.Write(header); err != nil {
+		return 0, err
+	}
+
+	word := make([]byte, 8)
+	for _, b := range f.bits {
+		binary.BigEndian.PutUint64(word, b)
+		if _, err := bw.Write(word); err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(header) + 8*len(f.bits)), bw.Flush()
+}
+
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, 20)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, ErrInvalidFilter
+	}
+	if [4]byte(header[:4]) != bloomMagic {
+		return nil, ErrInvalidFilter
+	}
+
+	f := &BloomFilter{
+		m:      binary.BigEndian.Uint64(header[4:]),
+		hashes: binary.BigEndian.Uint64(header[12:]),
+	}
+	if f.m == 0 || f.hashes == 0 || f.hashes > 64 {
+		return nil, ErrInvalidFilter
+	}
+
+	f.bits = make([]uint64, (f.m+63)/64)
+	word := make([]byte, 8)
+	for i := range f.bits {
+		if _, err := io.ReadFull(br, word); err != nil {
+			return nil, ErrInvalidFilter
+		}
+		f.bits[i] = binary.BigEndian.Uint64(word)
+	}
+
+	return f, nil
+}
+
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadBloomFilter(file)
+}
+
+// ReadHIBP calls fn with every hash of a Have I Been Pwned dump seen at
+// least minCount times. The dump is either one file of HASH:COUNT lines or
+// a directory of range files named by the 5 character hash prefix and
+// holding SUFFIX:COUNT lines.
+func ReadHIBP(path string, minCount int, fn func(sum [sha1.Size]byte)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return readHIBPFile(path, "", minCount, fn)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		prefix := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if len(prefix) != 5 {
+			continue
+		}
+		if err = readHIBPFile(filepath.Join(path, entry.Name()), prefix, minCount, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readHIBPFile(path string, prefix string, minCount int, fn func(sum [sha1.Size]byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		hash, count, _ := strings.Cut(line, ":")
+		if n, err := strconv.Atoi(count); err == nil && n < minCount {
+			continue
+		}
+
+		b, err := hex.DecodeString(prefix + hash)
+		if err != nil || len(b) != sha1.Size {
+			continue
+		}
+		fn([sha1.Size]byte(b))
+	}
+
+	return scanner.Err()
+}
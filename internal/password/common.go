@@ -0,0 +1,44 @@
+package password
+
+// commonPasswords are the most used passwords, most popular first. The rank
+// of a password is the number of guesses an attacker needs to reach it.
+var commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234",
+	"111111", "1234567", "dragon", "123123", "baseball", "abc123", "football",
+	"monkey", "letmein", "696969", "shadow", "master", "666666", "qwertyuiop",
+	"123321", "mustang", "1234567890", "michael", "654321", "superman",
+	"1qaz2wsx", "7777777", "121212", "000000", "qazwsx", "123qwe", "killer",
+	"trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter", "buster",
+	"soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou",
+	"2000", "charlie", "robert", "thomas", "hockey", "ranger", "daniel",
+	"starwars", "klaster", "112233", "george", "computer", "michelle",
+	"jessica", "pepper", "1111", "zxcvbn", "555555", "11111111", "131313",
+	"freedom", "777777", "pass", "maggie", "159753", "aaaaaa", "ginger",
+	"princess", "joshua", "cheese", "amanda", "summer", "love", "ashley",
+	"nicole", "chelsea", "biteme", "matthew", "access", "yankees",
+	"987654321", "dallas", "austin", "thunder", "taylor", "matrix",
+	"mobilemail", "mom", "monitor", "monitoring", "montana", "moon", "moscow",
+	"admin", "welcome", "login", "passw0rd", "qwerty123", "password1",
+	"123456a", "a123456", "1q2w3e4r", "1q2w3e", "qwe123", "zaq12wsx",
+	"q1w2e3r4", "asdfghjkl", "1q2w3e4r5t", "lovely", "888888", "secret",
+	"solo", "hello", "flower", "hottie", "loveme", "zaq1zaq1", "000000000",
+	"samsung", "google", "internet", "whatever", "nothing", "maxim",
+	"natasha", "marina", "anastasia", "sergey", "dmitriy", "alexander",
+	"vladimir", "olga", "svetlana", "tatiana", "elena", "irina", "andrey",
+	"alexey", "nikita", "ivan", "anna", "masha", "sasha", "parol", "parol123",
+	"пароль", "йцукен", "qwertyu", "privet", "привет", "kristina",
+	"ekaterina", "atomhack", "atom", "hack", "user", "test", "test123",
+	"guest", "root", "default", "changeme", "sunshine1", "football1",
+	"baseball1", "superman1", "iloveyou1", "monkey1", "dragon1", "master1",
+	"princess1", "letmein1",
+}
+
+var commonRanks = make(map[string]int, len(commonPasswords))
+
+func init() {
+	for i, word := range commonPasswords {
+		if _, ok := commonRanks[word]; !ok {
+			commonRanks[word] = i + 1
+		}
+	}
+}
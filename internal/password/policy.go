@@ -0,0 +1,128 @@
+package password
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/certified-juniors/AtomHack/internal/domain"
+)
+
+// Policy is the set of rules a new password has to satisfy. Zero values turn
+// the corresponding rule off.
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// MinClasses is how many of lowercase, uppercase, digits and symbols
+	// have to be present.
+	MinClasses int
+	// MinScore is the lowest acceptable Strength score, 0 to 4.
+	MinScore int
+	// Breached rejects passwords known from data breaches, nil skips it.
+	Breached BreachChecker
+}
+
+// Check validates the password against every rule and reports all violated
+// ones at once. Personal values such as the email and the name must not be
+// contained in the password.
+func (p Policy) Check(password []byte, personal ...string) error {
+	var violations []domain.PasswordViolation
+
+	length := utf8.RuneCount(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violations = append(violations, domain.PasswordViolation{
+			Rule:    domain.PasswordRuleMinLength,
+			Message: "password must be at least " + strconv.Itoa(p.MinLength) + " characters long",
+		})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, domain.PasswordViolation{
+			Rule:    domain.PasswordRuleMaxLength,
+			Message: "password must be at most " + strconv.Itoa(p.MaxLength) + " characters long",
+		})
+	}
+	if p.MinClasses > 0 && classes(password) < p.MinClasses {
+		violations = append(violations, domain.PasswordViolation{
+			Rule: domain.PasswordRuleCharacterClasses,
+			Message: "password must contain at least " + strconv.Itoa(p.MinClasses) +
+				" of lowercase letters, uppercase letters, digits and symbols",
+		})
+	}
+	if containsPersonal(password, personal) {
+		violations = append(violations, domain.PasswordViolation{
+			Rule:    domain.PasswordRulePersonalInfo,
+			Message: "password must not contain your email or name",
+		})
+	}
+	// estimating a password that is too long anyway is wasted work
+	if p.MinScore > 0 && (p.MaxLength <= 0 || length <= p.MaxLength) {
+		if score := Strength(password, personal...); score < p.MinScore {
+			violations = append(violations, domain.PasswordViolation{
+				Rule:    domain.PasswordRuleStrength,
+				Message: "password is too easy to guess, strength " + strconv.Itoa(score) + " of 4 is below " + strconv.Itoa(p.MinScore),
+			})
+		}
+	}
+
+	if p.Breached != nil && p.Breached.Breached(password) {
+		violations = append(violations, domain.PasswordViolation{
+			Rule:    domain.PasswordRuleBreached,
+			Message: "password has appeared in a data breach, choose another one",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &domain.PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+func classes(password []byte) int {
+	var lower, upper, digit, symbol int
+	for _, r := range string(password) {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
+
+// containsPersonal checks the password against the personal values and the
+// local part of emails among them. Values shorter than 3 characters are too
+// common to be rejected.
+func containsPersonal(password []byte, personal []string) bool {
+	lowered := strings.ToLower(string(password))
+	for _, value := range personalTokens(personal) {
+		if utf8.RuneCountInString(value) >= 3 && strings.Contains(lowered, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func personalTokens(personal []string) []string {
+	tokens := make([]string, 0, len(personal)*2)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		tokens = append(tokens, value)
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			tokens = append(tokens, local)
+		}
+	}
+
+	return tokens
+}
@@ -0,0 +1,293 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// The estimator follows zxcvbn: the password is covered by the cheapest
+// sequence of patterns (common passwords, personal values, keyboard walks,
+// sequences, repeats and years), unmatched characters are brute forced, and
+// the number of guesses is bucketed into a score.
+const (
+	bruteforceCardinality = 10
+	minSubmatchGuesses    = 50
+	maxDictionaryWord     = 24
+	// maxEstimated bounds the work on long inputs, characters past it are
+	// counted as brute forced
+	maxEstimated = 128
+)
+
+var keyboardRows = []string{
+	"1234567890-=",
+	"qwertyuiop[]",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"йцукенгшщзхъ",
+	"фывапролджэ",
+	"ячсмитьбю",
+}
+
+var leet = map[rune]rune{
+	'@': 'a', '4': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i',
+	'|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z',
+}
+
+type match struct {
+	start, end int
+	guesses    float64
+}
+
+// estimator remembers the guesses of every substring it has estimated, so
+// that nested repeats are estimated once.
+type estimator struct {
+	dictionary map[string]int
+	memo       map[string]float64
+}
+
+// Strength scores the password from 0, too guessable, to 4, very
+// unguessable. Personal values count as the most likely dictionary words.
+func Strength(password []byte, personal ...string) int {
+	guesses := Guesses(password, personal...)
+	switch {
+	case guesses < 1e3+5:
+		return 0
+	case guesses < 1e6+5:
+		return 1
+	case guesses < 1e8+5:
+		return 2
+	case guesses < 1e10+5:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Guesses estimates how many guesses an attacker needs for the password.
+func Guesses(password []byte, personal ...string) float64 {
+	dictionary := make(map[string]int, len(commonRanks)+len(personal)*2)
+	for word, rank := range commonRanks {
+		dictionary[word] = rank
+	}
+	for _, token := range personalTokens(personal) {
+		dictionary[token] = 1
+	}
+
+	runes := []rune(string(password))
+	rest := 1.0
+	if len(runes) > maxEstimated {
+		rest = math.Pow(bruteforceCardinality, float64(len(runes)-maxEstimated))
+		runes = runes[:maxEstimated]
+	}
+
+	e := &estimator{dictionary: dictionary, memo: make(map[string]float64)}
+	return e.guesses(runes) * rest
+}
+
+func (e *estimator) guesses(password []rune) float64 {
+	n := len(password)
+	if n == 0 {
+		return 1
+	}
+	if g, ok := e.memo[string(password)]; ok {
+		return g
+	}
+
+	var matches []match
+	matches = append(matches, dictionaryMatches(password, e.dictionary)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, keyboardMatches(password)...)
+	matches = append(matches, e.repeatMatches(password)...)
+	matches = append(matches, yearMatches(password)...)
+
+	byEnd := make([][]match, n+1)
+	for _, m := range matches {
+		if m.end-m.start < n {
+			m.guesses = math.Max(m.guesses, minSubmatchGuesses)
+		}
+		byEnd[m.end] = append(byEnd[m.end], m)
+	}
+
+	// best[i] is the cheapest way to guess the first i characters
+	best := make([]float64, n+1)
+	best[0] = 1
+	for i := 1; i <= n; i++ {
+		best[i] = best[i-1] * bruteforceCardinality
+		for _, m := range byEnd[i] {
+			best[i] = math.Min(best[i], best[m.start]*m.guesses)
+		}
+	}
+	e.memo[string(password)] = best[n]
+
+	return best[n]
+}
+
+func dictionaryMatches(password []rune, dictionary map[string]int) []match {
+	lower := []rune(strings.ToLower(string(password)))
+	unleet := make([]rune, len(lower))
+	for i, r := range lower {
+		if s, ok := leet[r]; ok {
+			unleet[i] = s
+		} else {
+			unleet[i] = r
+		}
+	}
+
+	var matches []match
+	for i := range lower {
+		for j := i + 3; j <= len(lower) && j-i <= maxDictionaryWord; j++ {
+			word := string(lower[i:j])
+			variations := caseVariations(password[i:j])
+			if rank, ok := dictionary[word]; ok {
+				matches = append(matches, match{i, j, float64(rank) * variations})
+			}
+			if rank, ok := dictionary[reverse(word)]; ok {
+				matches = append(matches, match{i, j, float64(rank) * variations * 2})
+			}
+			if substituted := string(unleet[i:j]); substituted != word {
+				if rank, ok := dictionary[substituted]; ok {
+					matches = append(matches, match{i, j, float64(rank) * variations * 2})
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// caseVariations is the number of ways an attacker tries to capitalize a
+// word: lowercase is tried first, then the capital first letter or all
+// capitals, then any mix.
+func caseVariations(word []rune) float64 {
+	var upper int
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 1
+	case upper == len(word) || (upper == 1 && unicode.IsUpper(word[0])):
+		return 2
+	default:
+		return math.Pow(2, float64(min(upper, 10)))
+	}
+}
+
+// sequenceMatches finds runs like abc, 9753 or zyx with a constant step.
+func sequenceMatches(password []rune) []match {
+	var matches []match
+	for i := 0; i+2 < len(password); {
+		delta := password[i+1] - password[i]
+		j := i + 1
+		for j+1 < len(password) && password[j+1]-password[j] == delta {
+			j++
+		}
+		if j-i >= 2 && delta != 0 && delta >= -5 && delta <= 5 {
+			base := 26.0
+			switch {
+			case strings.ContainsRune("aAzZ019яЯаА", password[i]):
+				base = 4
+			case unicode.IsDigit(password[i]):
+				base = 10
+			}
+			guesses := base * float64(j-i+1)
+			if delta < 0 {
+				guesses *= 2
+			}
+			if delta != 1 && delta != -1 {
+				guesses *= 2
+			}
+			matches = append(matches, match{i, j + 1, guesses})
+		}
+		i = j
+	}
+
+	return matches
+}
+
+// keyboardMatches finds walks along a keyboard row such as qwerty or asdf.
+func keyboardMatches(password []rune) []match {
+	lower := []rune(strings.ToLower(string(password)))
+
+	var matches []match
+	for i := 0; i+3 < len(lower); {
+		j := i
+		for j+1 < len(lower) && adjacent(lower[j], lower[j+1]) {
+			j++
+		}
+		if j-i >= 3 {
+			matches = append(matches, match{i, j + 1, float64(len(keyboardRows)*10*(j-i+1)) * caseVariations(password[i:j+1])})
+		}
+		i = max(j, i+1)
+	}
+
+	return matches
+}
+
+func adjacent(a, b rune) bool {
+	for _, row := range keyboardRows {
+		keys := []rune(row)
+		for k := range keys {
+			if keys[k] != a {
+				continue
+			}
+			if (k > 0 && keys[k-1] == b) || (k+1 < len(keys) && keys[k+1] == b) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// repeatMatches finds a block repeated at least twice, like aaa or abcabc.
+// Such a run costs as much as guessing the block once and the count.
+func (e *estimator) repeatMatches(password []rune) []match {
+	var matches []match
+	for i := range password {
+		for size := 1; i+2*size <= len(password); size++ {
+			// a repeat that extends to the left is matched from its start
+			if i >= size && string(password[i-size:i]) == string(password[i:i+size]) {
+				continue
+			}
+			count := 1
+			for i+(count+1)*size <= len(password) && string(password[i+count*size:i+(count+1)*size]) == string(password[i:i+size]) {
+				count++
+			}
+			if count < 2 || count*size < 3 {
+				continue
+			}
+			block := e.guesses(password[i : i+size])
+			matches = append(matches, match{i, i + count*size, block * float64(count)})
+		}
+	}
+
+	return matches
+}
+
+// yearMatches finds recent years, which are guessed among about 120 values.
+func yearMatches(password []rune) []match {
+	var matches []match
+	for i := 0; i+4 <= len(password); i++ {
+		year := string(password[i : i+4])
+		if (strings.HasPrefix(year, "19") || strings.HasPrefix(year, "20")) &&
+			unicode.IsDigit(password[i+2]) && unicode.IsDigit(password[i+3]) {
+			matches = append(matches, match{i, i + 4, 120})
+		}
+	}
+
+	return matches
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+
+	return string(r)
+}